@@ -0,0 +1,40 @@
+package fileop
+
+import (
+	"io/fs"
+)
+
+// ObjectAttrs holds backend specific attributes of a file. The integrations
+// return it from fs.FileInfo.Sys; fields a backend does not know are left
+// empty. Listings usually carry fewer attributes than Stat.
+type ObjectAttrs struct {
+	// object stores
+	ETag         string
+	ContentType  string
+	StorageClass string
+	Metadata     map[string]string // user metadata, keys without vendor prefix
+
+	// HDFS
+	Owner       string
+	Group       string
+	Replication int
+	BlockSize   int64
+}
+
+// Attrs returns the ObjectAttrs carried by info, or an empty ObjectAttrs
+// if the backend does not provide any.
+func Attrs(info fs.FileInfo) *ObjectAttrs {
+	if attrs, ok := info.Sys().(*ObjectAttrs); ok && attrs != nil {
+		return attrs
+	}
+	return &ObjectAttrs{}
+}
+
+// StatAttrs returns the ObjectAttrs of the named file.
+func StatAttrs(fsys Stater, name string) (*ObjectAttrs, error) {
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return Attrs(info), nil
+}
@@ -0,0 +1,39 @@
+package fileop_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+)
+
+type attrsStater map[string]*fstest.MapFile
+
+func (s attrsStater) Stat(name string) (fs.FileInfo, error) {
+	return fstest.MapFS(s).Stat(name)
+}
+
+func TestStatAttrs(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	st := attrsStater{
+		"with.txt":    {Sys: &fileop.ObjectAttrs{ETag: "abc", Metadata: map[string]string{"k": "v"}}},
+		"without.txt": {},
+	}
+
+	attrs, err := fileop.StatAttrs(st, "with.txt")
+	assert.NoError(err)
+	assert.Equal("abc", attrs.ETag)
+	assert.Equal("v", attrs.Metadata["k"])
+
+	attrs, err = fileop.StatAttrs(st, "without.txt")
+	assert.NoError(err)
+	assert.Equal(&fileop.ObjectAttrs{}, attrs)
+
+	_, err = fileop.StatAttrs(st, "missing.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+}
@@ -0,0 +1,96 @@
+package fileop
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// ErrUnsupported is returned by helpers when a backend lacks the methods
+// needed for the requested operation.
+var ErrUnsupported = errors.New("operation not supported")
+
+// Copier is implemented by backends that copy and move files without
+// streaming the content through the caller, e.g. with CopyObject on object
+// stores. Existing destinations are overwritten.
+type Copier interface {
+	Copy(src, dst string) error
+	Move(src, dst string) error
+}
+
+// Copy copies src to dst within fsys. It uses Copier when implemented and
+// otherwise streams the content through Open and a FileWriterInterface or
+// ITargetUploader. Capabilities are detected with As, so that they are
+// found through middlewares.
+func Copy(fsys Reader, src, dst string) error {
+	if c, ok := As[Copier](fsys); ok {
+		return c.Copy(src, dst)
+	}
+	return copyStream(fsys, src, fsys, dst)
+}
+
+// Move moves src to dst within fsys. It uses Copier or Writer.Rename when
+// implemented and otherwise copies the content and removes src.
+func Move(fsys Reader, src, dst string) error {
+	if c, ok := As[Copier](fsys); ok {
+		return c.Move(src, dst)
+	}
+	if r, ok := As[interface {
+		DirCreator
+		Rename(oldPath, newPath string) error
+	}](fsys); ok {
+		if err := r.MkdirAll(path.Dir(CleanPath(dst)), 0755); err != nil {
+			return fmt.Errorf("mkdir: %w", err)
+		}
+		return r.Rename(src, dst)
+	}
+
+	remover, ok := As[interface{ Remove(name string) error }](fsys)
+	if !ok {
+		return fmt.Errorf("move %s: %w", src, ErrUnsupported)
+	}
+	if err := copyStream(fsys, src, fsys, dst); err != nil {
+		return err
+	}
+	if err := remover.Remove(src); err != nil {
+		return fmt.Errorf("remove %s: %w", src, err)
+	}
+	return nil
+}
+
+// copyStream copies src from the reader to dst on target, which must be a
+// FileWriterInterface or an ITargetUploader.
+func copyStream(from Reader, src string, target any, dst string) error {
+	rd, err := from.Open(src)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", src, err)
+	}
+	defer func() { _ = rd.Close() }()
+
+	if t, ok := As[FileWriterInterface](target); ok {
+		wt, err := NewFileWriter(t, dst, 0, NONE)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", dst, err)
+		}
+		defer func() {
+			if wt != nil {
+				_ = wt.Close()
+			}
+		}()
+		if _, err := io.Copy(wt, rd); err != nil {
+			return fmt.Errorf("copy %s: %w", src, err)
+		}
+		defer func() { wt = nil }()
+		if err := wt.Close(); err != nil {
+			return fmt.Errorf("close %s: %w", dst, err)
+		}
+		return nil
+	}
+	if t, ok := As[interface {
+		PutStream(reader io.Reader, remote string) error
+	}](target); ok {
+		return t.PutStream(rd, dst)
+	}
+	return fmt.Errorf("copy %s: %w", src, ErrUnsupported)
+}
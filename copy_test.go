@@ -0,0 +1,94 @@
+package fileop_test
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestCopyMove(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	wt, err := fileop.NewFileWriter(mfs, "/src/a.txt", 0, fileop.NONE)
+	assert.NoError(err)
+	_, err = wt.Write([]byte("hello"))
+	assert.NoError(err)
+	assert.NoError(wt.Close())
+
+	readAll := func(name string) string {
+		rd, err := mfs.Open(name)
+		assert.NoError(err)
+		defer func() { _ = rd.Close() }()
+		b, err := io.ReadAll(rd)
+		assert.NoError(err)
+		return string(b)
+	}
+
+	assert.NoError(fileop.Copy(mfs, "/src/a.txt", "/copy/b.txt"))
+	assert.Equal("hello", readAll("/src/a.txt"))
+	assert.Equal("hello", readAll("/copy/b.txt"))
+
+	assert.NoError(fileop.Move(mfs, "/copy/b.txt", "/moved/c.txt"))
+	assert.Equal("hello", readAll("/moved/c.txt"))
+	_, err = mfs.Stat("/copy/b.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+
+	assert.ErrorIs(fileop.Copy(mfs, "/missing", "/x"), fs.ErrNotExist)
+}
+
+// objectStore is an object store without Rename and Copier.
+type objectStore struct {
+	*memTarget
+}
+
+func (o objectStore) Open(name string) (io.ReadCloser, error) {
+	s, ok := o.get(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(strings.NewReader(s)), nil
+}
+
+func TestCopyMoveMiddleware(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	policy := fileop.RetryPolicy{BaseDelay: time.Millisecond}
+
+	// server side copy and move through the middleware
+	cfs := newCopierFS(t)
+	fsys, err := fileop.WithRetry[fileop.FileSystem](cfs, policy)
+	assert.NoError(err)
+	writeString(t, fsys, "/a.txt", "a")
+	assert.NoError(fileop.Copy(fsys, "/a.txt", "/b.txt"))
+	assert.NoError(fileop.Move(fsys, "/b.txt", "/c.txt"))
+	assert.Equal(1, cfs.Calls("copy"))
+	assert.Equal(1, cfs.Calls("move"))
+	assert.Equal("a", readString(t, cfs, "/c.txt"))
+
+	// object stores without Rename stream and remove
+	type store interface {
+		fileop.Reader
+		fileop.ITargetUploader
+	}
+	objects := objectStore{newMemTarget()}
+	assert.NoError(objects.PutStream(strings.NewReader("o"), "/a"))
+	wrapped, err := fileop.WithRetry[store](objects, policy)
+	assert.NoError(err)
+	assert.NoError(fileop.Copy(wrapped, "/a", "/b"))
+	assert.NoError(fileop.Move(wrapped, "/b", "/c"))
+	s, ok := objects.get("/c")
+	assert.True(ok)
+	assert.Equal("o", s)
+	_, ok = objects.get("/b")
+	assert.False(ok)
+}
@@ -0,0 +1,98 @@
+package fileop
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Error classes attached by the integrations in addition to fs.ErrNotExist,
+// fs.ErrPermission and fs.ErrExist. Use errors.Is to test for them, or the
+// IsRetryable and IsThrottled helpers.
+var (
+	// ErrThrottled means the backend rejected the request because of rate
+	// limiting (HTTP 429, S3 SlowDown, ...). It is retryable after a backoff.
+	ErrThrottled = errors.New("request throttled")
+	// ErrUnavailable means the backend failed temporarily (HTTP 5xx, request
+	// timeout, ...). It is retryable.
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// classifiedError attaches a class to a backend error without changing its
+// message. The original error stays reachable by errors.As.
+type classifiedError struct {
+	err   error
+	class error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.err, e.class} }
+
+// Classify returns err annotated so that errors.Is(err, class) is true.
+// It returns err unchanged if either is nil or err already matches class.
+func Classify(err, class error) error {
+	if err == nil || class == nil || errors.Is(err, class) {
+		return err
+	}
+	return &classifiedError{err: err, class: class}
+}
+
+// ClassifyHTTPStatus classifies err by the HTTP status code a backend
+// responded with. Codes without a matching class leave err unchanged.
+func ClassifyHTTPStatus(err error, status int) error {
+	return Classify(err, httpStatusClass(status))
+}
+
+func httpStatusClass(status int) error {
+	switch {
+	case status == http.StatusNotFound:
+		return fs.ErrNotExist
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return fs.ErrPermission
+	case status == http.StatusConflict, status == http.StatusPreconditionFailed:
+		return fs.ErrExist
+	case status == http.StatusTooManyRequests:
+		return ErrThrottled
+	case status == http.StatusRequestTimeout, status >= 500:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// IsThrottled reports whether err was caused by backend rate limiting.
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+// IsRetryable reports whether the operation that returned err may succeed
+// when retried: throttling, temporary backend failures, network timeouts and
+// dropped connections. Not-found, permission and cancellation errors, as well
+// as corrupted data, are never retryable, nor are network errors that
+// usually stem from configuration, such as unknown hosts or refused
+// connections.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission), errors.Is(err, fs.ErrExist):
+		return false
+	case errors.Is(err, ErrThrottled), errors.Is(err, ErrUnavailable):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,74 @@
+package fileop_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+)
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string { return fmt.Sprintf("status %d", e.status) }
+
+func TestClassifyHTTPStatus(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	for _, c := range []struct {
+		status    int
+		class     error
+		retryable bool
+	}{
+		{http.StatusNotFound, fs.ErrNotExist, false},
+		{http.StatusForbidden, fs.ErrPermission, false},
+		{http.StatusConflict, fs.ErrExist, false},
+		{http.StatusTooManyRequests, fileop.ErrThrottled, true},
+		{http.StatusServiceUnavailable, fileop.ErrUnavailable, true},
+		{http.StatusBadRequest, nil, false},
+	} {
+		orig := &statusError{status: c.status}
+		err := fmt.Errorf("put x: %w", fileop.ClassifyHTTPStatus(orig, c.status))
+
+		assert.Equal("put x: "+orig.Error(), err.Error())
+		if c.class != nil {
+			assert.ErrorIs(err, c.class)
+		}
+		assert.Equal(c.retryable, fileop.IsRetryable(err), c.status)
+		assert.Equal(c.class == fileop.ErrThrottled, fileop.IsThrottled(err), c.status)
+
+		var se *statusError
+		assert.True(errors.As(err, &se))
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	assert.False(fileop.IsRetryable(nil))
+	assert.False(fileop.IsRetryable(os.ErrNotExist))
+	assert.False(fileop.IsRetryable(fmt.Errorf("wrap: %w", context.Canceled)))
+	assert.False(fileop.IsRetryable(errors.New("boom")))
+	assert.True(fileop.IsRetryable(io.ErrUnexpectedEOF))
+	assert.True(fileop.IsRetryable(&os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}))
+	assert.True(fileop.IsRetryable(fileop.Classify(errors.New("slow down"), fileop.ErrThrottled)))
+
+	// dropped connections and timeouts are transient, dial failures are not
+	assert.True(fileop.IsRetryable(&net.OpError{Op: "read", Net: "tcp", Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}}))
+	assert.True(fileop.IsRetryable(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "minio", IsTimeout: true}}))
+	assert.False(fileop.IsRetryable(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "minio", IsNotFound: true}}))
+	assert.False(fileop.IsRetryable(&net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}))
+}
@@ -0,0 +1,19 @@
+package fileop
+
+import "time"
+
+// The tests are in package fileop_test since integration/afero, which they
+// use as backend, imports fileop.
+
+// CopyStream copies src of from to dst of target.
+var CopyStream = copyStream
+
+// Forward returns a middleware passing every call to fsys unchanged.
+func Forward(fsys any) FileSystemSimpleBucket {
+	return forwarder{fsys: fsys}
+}
+
+// Backoff returns the delay of policy before retry attempt.
+func Backoff(policy RetryPolicy, attempt int) time.Duration {
+	return newRetryFS(nil, policy).backoff(attempt)
+}
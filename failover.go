@@ -0,0 +1,265 @@
+package fileop
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a Failover when the circuits of all members
+// that could serve a call are open. It matches ErrUnavailable.
+var ErrCircuitOpen = Classify(errors.New("circuit open"), ErrUnavailable)
+
+// CircuitState is the state of the circuit breaker of a Failover member.
+type CircuitState int
+
+const (
+	// CircuitClosed passes calls to the member.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen skips the member until the cooldown ends.
+	CircuitOpen
+	// CircuitHalfOpen passes a single trial call after the cooldown.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// FailoverOptions configures NewFailover.
+type FailoverOptions struct {
+	// Members are the backends serving the same files, primary first.
+	Members []ISourceReader
+	// Threshold is the number of consecutive failures opening the circuit
+	// of a member, default 5.
+	Threshold int
+	// Cooldown is the time an open circuit skips its member before a trial
+	// call, default 30s.
+	Cooldown time.Duration
+	// Retryable reports the failures that count for the circuit breaker and
+	// are tried on the next member, default IsRetryable. Not-found errors
+	// are tried on the next member without counting as failures.
+	Retryable func(error) bool
+	// OnStateChange is called when the circuit of a member changes state.
+	OnStateChange func(member int, from, to CircuitState)
+	// Now returns the current time for cooldowns and LastFailure, default
+	// time.Now. Tests set it to control the cooldown.
+	Now func() time.Time
+}
+
+// MemberHealth is a snapshot of the health of a Failover member.
+type MemberHealth struct {
+	State               CircuitState
+	ConsecutiveFailures int
+	Calls               int64 // calls passed to the member
+	Failures            int64 // calls failed with a retryable error
+	LastError           error // last retryable error, nil if none
+	LastFailure         time.Time
+}
+
+// Failover is an ISourceReader reading from the first healthy member that
+// has the path. Calls failing with a retryable or not-found error are tried
+// again on the next member, and members failing repeatedly are skipped by a
+// circuit breaker until their cooldown ends. Errors while reading an opened
+// stream are not failed over.
+type Failover struct {
+	members []ISourceReader
+	opts    FailoverOptions
+
+	mu     sync.Mutex
+	health []memberState
+}
+
+type memberState struct {
+	MemberHealth
+	openedAt time.Time
+	trial    bool // a half-open trial call is running
+}
+
+var (
+	_ ISourceReader = (*Failover)(nil)
+	_ Stater        = (*Failover)(nil)
+)
+
+// NewFailover creates a failover of opts.Members.
+func NewFailover(opts FailoverOptions) *Failover {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = IsRetryable
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Failover{
+		members: opts.Members,
+		opts:    opts,
+		health:  make([]memberState, len(opts.Members)),
+	}
+}
+
+// Health returns the health of every member, in the order of Members.
+func (f *Failover) Health() []MemberHealth {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	health := make([]MemberHealth, len(f.health))
+	for i, m := range f.health {
+		health[i] = m.MemberHealth
+	}
+	return health
+}
+
+// setState changes the state of member i. The caller must hold f.mu; the
+// callback runs after it is released through the returned function.
+func (f *Failover) setState(i int, to CircuitState) func() {
+	from := f.health[i].State
+	if from == to {
+		return func() {}
+	}
+	f.health[i].State = to
+	if f.opts.OnStateChange == nil {
+		return func() {}
+	}
+	return func() { f.opts.OnStateChange(i, from, to) }
+}
+
+// allow reports whether member i may be called, moving an open circuit to
+// half-open after the cooldown.
+func (f *Failover) allow(i int) bool {
+	f.mu.Lock()
+	m := &f.health[i]
+	notify := func() {}
+	allowed := true
+	switch m.State {
+	case CircuitOpen:
+		if f.opts.Now().Sub(m.openedAt) < f.opts.Cooldown {
+			allowed = false
+			break
+		}
+		notify = f.setState(i, CircuitHalfOpen)
+		m.trial = true
+	case CircuitHalfOpen:
+		if m.trial {
+			allowed = false
+			break
+		}
+		m.trial = true
+	}
+	if allowed {
+		m.Calls++
+	}
+	f.mu.Unlock()
+	notify()
+	return allowed
+}
+
+// done records the outcome of a call of member i.
+func (f *Failover) done(i int, err error) {
+	f.mu.Lock()
+	m := &f.health[i]
+	m.trial = false
+	var notify func()
+	if err != nil && f.opts.Retryable(err) {
+		m.Failures++
+		m.ConsecutiveFailures++
+		m.LastError = err
+		m.LastFailure = f.opts.Now()
+		if m.State == CircuitHalfOpen || m.ConsecutiveFailures >= f.opts.Threshold {
+			m.openedAt = m.LastFailure
+			notify = f.setState(i, CircuitOpen)
+		} else {
+			notify = func() {}
+		}
+	} else {
+		m.ConsecutiveFailures = 0
+		notify = f.setState(i, CircuitClosed)
+	}
+	f.mu.Unlock()
+	notify()
+}
+
+// failoverCall runs fn on the members accepted by accept, nil for all, in order
+// until one succeeds or fails with an error that is not failed over.
+func failoverCall[R any](f *Failover, op, name string, accept func(ISourceReader) bool, fn func(ISourceReader) (R, error)) (R, error) {
+	var zero R
+	var lastErr error
+	tried := false
+	for i, m := range f.members {
+		if accept != nil && !accept(m) {
+			continue
+		}
+		tried = true
+		if !f.allow(i) {
+			continue
+		}
+		v, err := fn(m)
+		f.done(i, err)
+		if err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) && !f.opts.Retryable(err) {
+			return zero, err
+		}
+		lastErr = err
+	}
+	switch {
+	case !tried:
+		lastErr = &fs.PathError{Op: op, Path: name, Err: ErrUnsupported}
+	case lastErr == nil:
+		lastErr = &fs.PathError{Op: op, Path: name, Err: ErrCircuitOpen}
+	}
+	return zero, lastErr
+}
+
+func (f *Failover) Open(name string) (io.ReadCloser, error) {
+	return failoverCall(f, "open", name, nil, func(m ISourceReader) (io.ReadCloser, error) {
+		return m.Open(name)
+	})
+}
+
+func (f *Failover) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	return failoverCall(f, "readdir", dirname, nil, func(m ISourceReader) ([]fs.FileInfo, error) {
+		return m.Readdir(dirname, n)
+	})
+}
+
+func (f *Failover) Readdirnames(dirname string, n int) ([]string, error) {
+	return failoverCall(f, "readdir", dirname, nil, func(m ISourceReader) ([]string, error) {
+		return m.Readdirnames(dirname, n)
+	})
+}
+
+// Stat fails over like Open, skipping members without Stater.
+func (f *Failover) Stat(name string) (fs.FileInfo, error) {
+	isStater := func(m ISourceReader) bool {
+		_, ok := As[Stater](m)
+		return ok
+	}
+	return failoverCall(f, "stat", name, isStater, func(m ISourceReader) (fs.FileInfo, error) {
+		return m.(Stater).Stat(name)
+	})
+}
+
+// Close closes every member.
+func (f *Failover) Close() error {
+	var errs []error
+	for _, m := range f.members {
+		errs = append(errs, m.Close())
+	}
+	return errors.Join(errs...)
+}
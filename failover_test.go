@@ -0,0 +1,91 @@
+package fileop_test
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// downFS fails every call with ErrUnavailable while down is set.
+type downFS struct {
+	*afero.Handler
+	down  atomic.Bool
+	opens atomic.Int64
+}
+
+func (d *downFS) Open(name string) (io.ReadCloser, error) {
+	d.opens.Add(1)
+	if d.down.Load() {
+		return nil, fileop.Classify(io.ErrClosedPipe, fileop.ErrUnavailable)
+	}
+	return d.Handler.Open(name)
+}
+
+func TestFailover(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	pfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	sfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	writeString(t, pfs, "/a.txt", "primary a")
+	writeString(t, sfs, "/a.txt", "secondary a")
+	writeString(t, sfs, "/archived.txt", "archived")
+	primary := &downFS{Handler: pfs}
+
+	var mu sync.Mutex
+	var changes []fileop.CircuitState
+	var now atomic.Int64 // nanoseconds since start
+	start := time.Now()
+	f := fileop.NewFailover(fileop.FailoverOptions{
+		Members:   []fileop.ISourceReader{primary, sfs},
+		Threshold: 2,
+		Cooldown:  time.Minute,
+		OnStateChange: func(_ int, _, to fileop.CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, to)
+		},
+		Now: func() time.Time { return start.Add(time.Duration(now.Load())) },
+	})
+	assert.Equal("primary a", readString(t, f, "/a.txt"))
+	assert.Equal("archived", readString(t, f, "/archived.txt"), "not found fails over")
+	info, err := f.Stat("/archived.txt")
+	assert.NoError(err)
+	assert.Equal("archived.txt", info.Name())
+
+	// a dead primary is skipped after Threshold failures
+	primary.down.Store(true)
+	for range 5 {
+		assert.Equal("secondary a", readString(t, f, "/a.txt"))
+	}
+	assert.EqualValues(4, primary.opens.Load())
+	health := f.Health()
+	assert.Equal(fileop.CircuitOpen, health[0].State)
+	assert.Equal(2, health[0].ConsecutiveFailures)
+	assert.ErrorIs(health[0].LastError, fileop.ErrUnavailable)
+	assert.Equal(fileop.CircuitClosed, health[1].State)
+
+	// after the cooldown a trial call closes the circuit again
+	primary.down.Store(false)
+	now.Add(int64(59 * time.Second))
+	assert.Equal("secondary a", readString(t, f, "/a.txt"))
+	now.Add(int64(time.Second))
+	assert.Equal("primary a", readString(t, f, "/a.txt"))
+	assert.Equal(fileop.CircuitClosed, f.Health()[0].State)
+	mu.Lock()
+	assert.Equal([]fileop.CircuitState{fileop.CircuitOpen, fileop.CircuitHalfOpen, fileop.CircuitClosed}, changes)
+	mu.Unlock()
+
+	_, err = f.Open("/missing.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+}
@@ -0,0 +1,354 @@
+// Package fileopcache provides caching wrappers for fileop backends.
+package fileopcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// tmpSuffix marks downloads in progress, removed when the cache starts.
+const tmpSuffix = ".tmp"
+
+// DiskOptions configures NewDisk.
+type DiskOptions struct {
+	Dir      string        // local cache directory, created if missing
+	MaxBytes int64         // size limit of the cached files, 0 for no limit
+	TTL      time.Duration // time until a cached file is validated again
+
+	// Local stores the cached files, default the afero Disk backend.
+	Local fileop.FileSystem
+}
+
+// DiskStats are the counters of a Disk cache.
+type DiskStats struct {
+	Hits      int64 // opens served from the cache
+	Misses    int64 // opens that downloaded the file
+	Coalesced int64 // opens that waited for a download of another open
+	Evictions int64 // files removed to stay below MaxBytes
+	Files     int   // cached files
+	Bytes     int64 // size of the cached files
+}
+
+// Disk is a read-through cache of a source on local disk. Files are keyed
+// by path and the version reported by the source's Stat (ETag, size and
+// modification time), so a changed remote file is downloaded again once its
+// TTL expired. Sources without fileop.Stater are keyed by path only and
+// downloaded again after the TTL. Cached files survive restarts and are
+// evicted least recently used first.
+type Disk struct {
+	src   fileop.ISourceReader
+	local fileop.FileSystem
+	opts  DiskOptions
+
+	mu    sync.Mutex
+	files map[string]*cachedFile // by key
+	names map[string]string      // path to the key of its last version
+	lru   *list.List             // of *cachedFile, most recent first
+	bytes int64
+	calls map[string]*download // by key
+
+	hits, misses, coalesced, evictions atomic.Int64
+}
+
+type cachedFile struct {
+	key       string
+	name      string // empty until opened in this run
+	size      int64
+	validated time.Time
+	elem      *list.Element
+}
+
+type download struct {
+	done chan struct{}
+	err  error
+}
+
+var _ fileop.ISourceReader = (*Disk)(nil)
+
+// NewDisk creates a cache of src in opts.Dir and registers the files left
+// there by a previous run.
+func NewDisk(src fileop.ISourceReader, opts DiskOptions) (*Disk, error) {
+	if opts.Local == nil {
+		local, err := afero.New(afero.Disk)
+		if err != nil {
+			return nil, fmt.Errorf("new disk: %w", err)
+		}
+		opts.Local = local
+	}
+	c := &Disk{
+		src:   src,
+		local: opts.Local,
+		opts:  opts,
+		files: make(map[string]*cachedFile),
+		names: make(map[string]string),
+		lru:   list.New(),
+		calls: make(map[string]*download),
+	}
+	if err := c.local.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir %s: %w", opts.Dir, err)
+	}
+	infos, err := c.local.Readdir(opts.Dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", opts.Dir, err)
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(info.Name(), tmpSuffix) {
+			_ = c.local.Remove(c.localPath(info.Name()))
+			continue
+		}
+		// the path is unknown until it is opened again, never fresh
+		c.add(info.Name(), info.Size(), time.Time{})
+	}
+	c.mu.Lock()
+	c.evict(nil)
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Stats returns the current counters.
+func (c *Disk) Stats() DiskStats {
+	c.mu.Lock()
+	files, bytes := len(c.files), c.bytes
+	c.mu.Unlock()
+	return DiskStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Evictions: c.evictions.Load(),
+		Files:     files,
+		Bytes:     bytes,
+	}
+}
+
+// Open returns the cached copy of name, downloading it first if it is
+// missing or outdated. Concurrent opens of the same file share a single
+// download.
+func (c *Disk) Open(name string) (io.ReadCloser, error) {
+	name = fileop.CleanPath(name)
+	if rd := c.openFresh(name); rd != nil {
+		return rd, nil
+	}
+
+	version, known, err := c.version(name)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(name, version)
+	if known {
+		if rd := c.openValidated(name, key); rd != nil {
+			return rd, nil
+		}
+	}
+
+	for {
+		leader, err := c.fetch(name, key)
+		if err != nil {
+			return nil, err
+		}
+		rd, err := c.local.Open(c.localPath(key))
+		if err == nil {
+			if leader {
+				c.misses.Add(1)
+			} else {
+				c.coalesced.Add(1)
+			}
+			return rd, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("open cached %s: %w", name, err)
+		}
+		// evicted right after the download, fetch again
+	}
+}
+
+// openFresh opens the last version of name if it was validated within TTL.
+func (c *Disk) openFresh(name string) io.ReadCloser {
+	c.mu.Lock()
+	f := c.files[c.names[name]]
+	if f == nil || time.Since(f.validated) >= c.opts.TTL {
+		c.mu.Unlock()
+		return nil
+	}
+	c.lru.MoveToFront(f.elem)
+	c.mu.Unlock()
+	return c.openHit(f.key)
+}
+
+// openValidated opens key if it is cached and marks it validated.
+func (c *Disk) openValidated(name, key string) io.ReadCloser {
+	c.mu.Lock()
+	f := c.files[key]
+	if f == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	f.validated = time.Now()
+	f.name = name
+	c.names[name] = key
+	c.lru.MoveToFront(f.elem)
+	c.mu.Unlock()
+	return c.openHit(key)
+}
+
+func (c *Disk) openHit(key string) io.ReadCloser {
+	rd, err := c.local.Open(c.localPath(key))
+	if err != nil {
+		return nil
+	}
+	c.hits.Add(1)
+	return rd
+}
+
+// version identifies the content of name on the source, and reports
+// whether the source could tell.
+func (c *Disk) version(name string) (string, bool, error) {
+	st, ok := fileop.As[fileop.Stater](c.src)
+	if !ok {
+		return "", false, nil
+	}
+	info, err := st.Stat(name)
+	if err != nil {
+		return "", false, fmt.Errorf("stat %s: %w", name, err)
+	}
+	return fmt.Sprintf("%s|%d|%d", fileop.Attrs(info).ETag, info.Size(), info.ModTime().UnixNano()), true, nil
+}
+
+// fetch downloads name to key unless another call is already doing so, and
+// reports whether this call downloaded it.
+func (c *Disk) fetch(name, key string) (bool, error) {
+	c.mu.Lock()
+	if d, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-d.done
+		return false, d.err
+	}
+	d := &download{done: make(chan struct{})}
+	c.calls[key] = d
+	c.mu.Unlock()
+
+	size, err := c.download(name, key)
+	c.mu.Lock()
+	delete(c.calls, key)
+	if err == nil {
+		if old := c.files[c.names[name]]; old != nil && old.key != key {
+			c.remove(old) // outdated version
+		}
+		f := c.add(key, size, time.Now())
+		f.name = name
+		c.names[name] = key
+		c.evict(f)
+	}
+	c.mu.Unlock()
+
+	d.err = err
+	close(d.done)
+	return true, err
+}
+
+func (c *Disk) download(name, key string) (int64, error) {
+	rd, err := c.src.Open(name)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", name, err)
+	}
+	defer func() { _ = rd.Close() }()
+
+	tmp := c.localPath(key + tmpSuffix)
+	wt, err := c.local.Create(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", tmp, err)
+	}
+	n, err := io.Copy(wt, rd)
+	if closeErr := wt.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = c.local.Rename(tmp, c.localPath(key))
+	}
+	if err != nil {
+		_ = c.local.Remove(tmp)
+		return 0, fmt.Errorf("download %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// add registers a cached file. The caller must hold c.mu unless the cache
+// is not shared yet.
+func (c *Disk) add(key string, size int64, validated time.Time) *cachedFile {
+	if f, ok := c.files[key]; ok {
+		c.bytes -= f.size
+		c.lru.Remove(f.elem)
+	}
+	f := &cachedFile{key: key, size: size, validated: validated}
+	f.elem = c.lru.PushFront(f)
+	c.files[key] = f
+	c.bytes += size
+	return f
+}
+
+// evict removes the least recently used files other than keep until the
+// cache fits MaxBytes. The caller must hold c.mu. Open readers of evicted
+// files keep working on systems that allow removing open files.
+func (c *Disk) evict(keep *cachedFile) {
+	if c.opts.MaxBytes <= 0 {
+		return
+	}
+	for elem := c.lru.Back(); elem != nil && c.bytes > c.opts.MaxBytes; {
+		f := elem.Value.(*cachedFile)
+		elem = elem.Prev()
+		if f == keep {
+			continue
+		}
+		c.remove(f)
+		c.evictions.Add(1)
+	}
+}
+
+// remove deletes a cached file. The caller must hold c.mu.
+func (c *Disk) remove(f *cachedFile) {
+	c.lru.Remove(f.elem)
+	delete(c.files, f.key)
+	if c.names[f.name] == f.key {
+		delete(c.names, f.name)
+	}
+	c.bytes -= f.size
+	_ = c.local.Remove(c.localPath(f.key))
+}
+
+func (c *Disk) localPath(key string) string {
+	return path.Join(c.opts.Dir, key)
+}
+
+// cacheKey names the cached copy of a version of a file.
+func cacheKey(name, version string) string {
+	sum := sha256.Sum256([]byte(name + "\x00" + version))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Disk) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	return c.src.Readdir(dirname, n)
+}
+
+func (c *Disk) Readdirnames(dirname string, n int) ([]string, error) {
+	return c.src.Readdirnames(dirname, n)
+}
+
+// Close closes the source. Cached files are kept for the next run.
+func (c *Disk) Close() error {
+	return c.src.Close()
+}
@@ -0,0 +1,108 @@
+package fileopcache
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/fileutil"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// slowSource counts the opens and delays them to overlap concurrent calls.
+type slowSource struct {
+	*afero.Handler
+	opens atomic.Int64
+}
+
+func (s *slowSource) Open(name string) (io.ReadCloser, error) {
+	s.opens.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	return s.Handler.Open(name)
+}
+
+func readAll(t *testing.T, src fileop.Reader, name string) string {
+	rd, err := src.Open(name)
+	require.NoError(t, err)
+	defer func() { _ = rd.Close() }()
+	b, err := io.ReadAll(rd)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDisk(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	src := &slowSource{Handler: mfs}
+	assert.NoError(fileutil.WriteFile(mfs, "/dict/a.txt", strings.NewReader("aaaa")))
+	assert.NoError(fileutil.WriteFile(mfs, "/dict/b.txt", strings.NewReader("bbbb")))
+
+	local, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	opts := DiskOptions{Dir: "/cache", MaxBytes: 6, TTL: time.Hour, Local: local}
+	c, err := NewDisk(src, opts)
+	assert.NoError(err)
+
+	var wg sync.WaitGroup
+	contents := make([]string, 5)
+	errs := make([]error, 5)
+	for i := range contents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rd, err := c.Open("/dict/a.txt")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer func() { _ = rd.Close() }()
+			b, err := io.ReadAll(rd)
+			contents[i], errs[i] = string(b), err
+		}()
+	}
+	wg.Wait()
+	for i := range contents {
+		assert.NoError(errs[i])
+		assert.Equal("aaaa", contents[i])
+	}
+	assert.EqualValues(1, src.opens.Load(), "concurrent opens share a download")
+	stats := c.Stats()
+	assert.EqualValues(1, stats.Misses)
+	assert.EqualValues(5, stats.Hits+stats.Misses+stats.Coalesced)
+
+	assert.Equal("aaaa", readAll(t, c, "/dict//a.txt"))
+	assert.EqualValues(1, src.opens.Load())
+
+	// b evicts a to stay below MaxBytes
+	assert.Equal("bbbb", readAll(t, c, "/dict/b.txt"))
+	stats = c.Stats()
+	assert.EqualValues(1, stats.Evictions)
+	assert.Equal(1, stats.Files)
+	assert.EqualValues(4, stats.Bytes)
+
+	// a restart keeps b, which is validated by Stat before use
+	c, err = NewDisk(src, opts)
+	assert.NoError(err)
+	assert.Equal(1, c.Stats().Files)
+	assert.Equal("bbbb", readAll(t, c, "/dict/b.txt"))
+	assert.EqualValues(2, src.opens.Load())
+	assert.EqualValues(1, c.Stats().Hits)
+
+	// a changed file is downloaded again after the TTL
+	opts.TTL = 0
+	c, err = NewDisk(src, opts)
+	assert.NoError(err)
+	assert.NoError(fileutil.WriteFile(mfs, "/dict/b.txt", strings.NewReader("bbb")))
+	assert.Equal("bbb", readAll(t, c, "/dict/b.txt"))
+	assert.EqualValues(3, src.opens.Load())
+	assert.Equal(1, c.Stats().Files, "outdated version removed")
+}
@@ -0,0 +1,94 @@
+// Package fileopotel traces fileop operations with OpenTelemetry.
+package fileopotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/marsgopher/fileop"
+)
+
+const instrumentationName = "github.com/marsgopher/fileop/fileopotel"
+
+// Attribute keys set on every span.
+const (
+	BackendKey    = attribute.Key("fileop.backend")
+	BucketKey     = attribute.Key("fileop.bucket")
+	PathKey       = attribute.Key("fileop.path")
+	BytesKey      = attribute.Key("fileop.bytes")
+	ErrorClassKey = attribute.Key("fileop.error_class")
+)
+
+// Options configures WithTracing.
+type Options struct {
+	Backend string // backend mode, e.g. "hdfs" or "minio"
+	Bucket  string // bucket of fsys, if any; buckets from Bucket(name) use name
+
+	// Context is the parent of the spans, default context.Background().
+	// Operations have no context of their own, so wrap per request to nest
+	// the spans in the request trace.
+	Context context.Context
+
+	// TracerProvider defaults to the global provider.
+	TracerProvider trace.TracerProvider
+}
+
+// WithTracing returns fsys with a span for every operation, named
+// "fileop <op>" and annotated with backend, bucket, path and bytes. Spans of
+// Open and Create end when the returned stream is closed. See
+// fileop.WithRetry for the meaning of T.
+func WithTracing[T any](fsys T, opts Options) (T, error) {
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+
+	return fileop.WithObserver(fsys, &tracer{
+		tracer:  opts.TracerProvider.Tracer(instrumentationName),
+		ctx:     opts.Context,
+		backend: opts.Backend,
+		bucket:  opts.Bucket,
+	})
+}
+
+// tracer is the fileop.BucketObserver of WithTracing.
+type tracer struct {
+	tracer  trace.Tracer
+	ctx     context.Context
+	backend string
+	bucket  string
+}
+
+// ForBucket annotates the spans of a bucket opened through Bucket(name) with
+// name instead of Options.Bucket.
+func (t *tracer) ForBucket(name string) fileop.Observer {
+	bt := *t
+	bt.bucket = name
+	return &bt
+}
+
+func (t *tracer) Begin(op fileop.Op, name string) func(n int64, err error) {
+	attrs := []attribute.KeyValue{BackendKey.String(t.backend), PathKey.String(name)}
+	if t.bucket != "" {
+		attrs = append(attrs, BucketKey.String(t.bucket))
+	}
+	_, span := t.tracer.Start(t.ctx, "fileop "+string(op),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return func(n int64, err error) {
+		span.SetAttributes(BytesKey.Int64(n))
+		if err != nil {
+			span.SetAttributes(ErrorClassKey.String(fileop.ErrorClass(err)))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
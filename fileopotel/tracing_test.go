@@ -0,0 +1,106 @@
+package fileopotel
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/integration/afero"
+	"github.com/marsgopher/fileop/simplefs"
+)
+
+func TestWithTracing(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	fsys, err := WithTracing[fileop.FileSystem](mfs, Options{
+		Backend:        "memory",
+		Bucket:         "logs",
+		Context:        ctx,
+		TracerProvider: tp,
+	})
+	assert.NoError(err)
+
+	wt, err := fsys.Create("/a.txt")
+	assert.NoError(err)
+	_, err = wt.Write([]byte("hello"))
+	assert.NoError(err)
+	assert.Empty(exporter.GetSpans(), "span ends on close")
+	assert.NoError(wt.Close())
+
+	rd, err := fsys.Open("/a.txt")
+	assert.NoError(err)
+	_, err = io.ReadAll(rd)
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+
+	_, err = fsys.Stat("/missing")
+	assert.Error(err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	assert.Len(spans, 4)
+	for i, name := range []string{"fileop create", "fileop open", "fileop stat"} {
+		span := spans[i]
+		assert.Equal(name, span.Name)
+		assert.Equal(parent.SpanContext().SpanID(), span.Parent.SpanID())
+		attrs := map[string]any{}
+		for _, kv := range span.Attributes {
+			attrs[string(kv.Key)] = kv.Value.AsInterface()
+		}
+		assert.Equal("memory", attrs["fileop.backend"])
+		assert.Equal("logs", attrs["fileop.bucket"])
+		if name == "fileop stat" {
+			assert.Equal("/missing", attrs["fileop.path"])
+			assert.Equal("not_exist", attrs["fileop.error_class"])
+			assert.Equal(codes.Error, span.Status.Code)
+			continue
+		}
+		assert.Equal("/a.txt", attrs["fileop.path"])
+		assert.Equal(int64(5), attrs["fileop.bytes"])
+	}
+}
+
+func TestWithTracingBucket(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	simple := &simplefs.WrapFS{Source: mfs, Target: &filetarget.WrapFS{Target: mfs}}
+	fsys, err := WithTracing[fileop.FileSystemSimpleBucket](simple, Options{
+		Backend:        "memory",
+		Bucket:         "logs",
+		TracerProvider: tp,
+	})
+	assert.NoError(err)
+
+	assert.False(fsys.Exist("/a.txt"))
+	assert.False(fsys.Bucket("images").Exist("/a.txt"))
+
+	spans := exporter.GetSpans()
+	assert.Len(spans, 2)
+	for i, bucket := range []string{"logs", "images"} {
+		attrs := map[string]any{}
+		for _, kv := range spans[i].Attributes {
+			attrs[string(kv.Key)] = kv.Value.AsInterface()
+		}
+		assert.Equal(bucket, attrs["fileop.bucket"])
+	}
+}
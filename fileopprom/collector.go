@@ -0,0 +1,92 @@
+// Package fileopprom exports the measurements of fileop.WithMetrics as
+// Prometheus metrics.
+package fileopprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marsgopher/fileop"
+)
+
+// Options configures a Collector.
+type Options struct {
+	Namespace string    // metric name prefix, default "fileop"
+	Buckets   []float64 // latency buckets in seconds, default prometheus.DefBuckets
+}
+
+// Collector is a fileop.MetricsHook and a prometheus.Collector with
+//
+//	<namespace>_operations_total{backend,op}
+//	<namespace>_errors_total{backend,op,class}
+//	<namespace>_operation_duration_seconds{backend,op}
+//	<namespace>_bytes_total{backend,op}
+//
+// where class is fileop.ErrorClass of the error.
+type Collector struct {
+	ops      *prometheus.CounterVec
+	errs     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	bytes    *prometheus.CounterVec
+}
+
+var _ fileop.MetricsHook = (*Collector)(nil)
+
+// NewCollector creates a Collector, which still has to be registered.
+func NewCollector(opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "fileop"
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+	return &Collector{
+		ops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "operations_total",
+			Help:      "Number of file operations.",
+		}, []string{"backend", "op"}),
+		errs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "errors_total",
+			Help:      "Number of failed file operations by error class.",
+		}, []string{"backend", "op", "class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of file operations, streams until closed.",
+			Buckets:   opts.Buckets,
+		}, []string{"backend", "op"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "bytes_total",
+			Help:      "Bytes read by open and written by create and uploads.",
+		}, []string{"backend", "op"}),
+	}
+}
+
+func (c *Collector) ObserveOp(backend string, op fileop.Op, duration time.Duration, bytes int64, err error) {
+	c.ops.WithLabelValues(backend, string(op)).Inc()
+	c.duration.WithLabelValues(backend, string(op)).Observe(duration.Seconds())
+	if bytes > 0 {
+		c.bytes.WithLabelValues(backend, string(op)).Add(float64(bytes))
+	}
+	if err != nil {
+		c.errs.WithLabelValues(backend, string(op), fileop.ErrorClass(err)).Inc()
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.ops.Describe(ch)
+	c.errs.Describe(ch)
+	c.duration.Describe(ch)
+	c.bytes.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.ops.Collect(ch)
+	c.errs.Collect(ch)
+	c.duration.Collect(ch)
+	c.bytes.Collect(ch)
+}
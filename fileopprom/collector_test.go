@@ -0,0 +1,52 @@
+package fileopprom
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	c := NewCollector(Options{})
+	reg := prometheus.NewPedanticRegistry()
+	assert.NoError(reg.Register(c))
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	var dst fileop.ITargetUploader = &filetarget.WrapFS{Target: mfs}
+	dst, err = fileop.WithMetrics(dst, "memory", c)
+	assert.NoError(err)
+	assert.NoError(dst.PutStream(strings.NewReader("hello"), "/a.txt"))
+	_, err = dst.Stat("/missing.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+
+	var fsys fileop.FileSystem = mfs
+	fsys, err = fileop.WithMetrics(fsys, "memory", c)
+	assert.NoError(err)
+	rd, err := fsys.Open("/a.txt")
+	assert.NoError(err)
+	_, err = io.Copy(io.Discard, rd)
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+
+	assert.Equal(1.0, testutil.ToFloat64(c.ops.WithLabelValues("memory", "put_stream")))
+	assert.Equal(5.0, testutil.ToFloat64(c.bytes.WithLabelValues("memory", "put_stream")))
+	assert.Equal(5.0, testutil.ToFloat64(c.bytes.WithLabelValues("memory", "open")))
+	assert.Equal(1.0, testutil.ToFloat64(c.errs.WithLabelValues("memory", "stat", "not_exist")))
+	assert.Equal(3, testutil.CollectAndCount(c, "fileop_operations_total"))
+	problems, err := testutil.CollectAndLint(c)
+	assert.NoError(err)
+	assert.Empty(problems)
+}
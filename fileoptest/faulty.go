@@ -0,0 +1,409 @@
+package fileoptest
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/marsgopher/fileop"
+)
+
+// ErrFault is an injected failure for Rule.Err. It is classified as
+// fileop.ErrUnavailable, so fileop.IsRetryable reports true.
+var ErrFault = fileop.Classify(errors.New("injected fault"), fileop.ErrUnavailable)
+
+// Rule describes a fault injected into the calls of a FaultyFS. A call
+// matches when Op and Path match; the fault applies to the matching calls
+// selected by Nth and Probability.
+type Rule struct {
+	Op   fileop.Op // operation to match, empty for all
+	Path string    // fileop.Match pattern of the cleaned path, empty for all
+
+	Nth         int     // select only the Nth matching call, counted from 1
+	Probability float64 // select matching calls with this probability
+
+	Latency time.Duration // delay before the call, e.g. for slow listings
+	Err     error         // error returned by the call
+	OnClose bool          // for Open and Create, return Err from Close of the stream instead
+
+	ShortRead  int   // return at most this many bytes per Read of opened streams
+	Truncate   bool  // silently drop written bytes beyond TruncateAt
+	TruncateAt int64 // with Truncate, the number of bytes kept by created files
+}
+
+// FaultyFS injects the faults of its rules into the calls it forwards. It
+// forwards the methods of every fileop interface, including uploads and
+// optional interfaces such as fileop.Copier, and implements
+// fileop.Unwrapper, so fileop.As detects the capabilities of the wrapped
+// file system. Buckets share the rules and call counts. It is safe for
+// concurrent use.
+type FaultyFS struct {
+	fileop.Forwarder
+	*faults
+}
+
+// faults is the state shared by a FaultyFS and its buckets.
+type faults struct {
+	rules []Rule
+
+	mu      sync.Mutex
+	matched []int // matching calls per rule
+	calls   map[fileop.Op]int
+	rand    *rand.Rand
+}
+
+var (
+	_ fileop.FileSystemWithCloser   = (*FaultyFS)(nil)
+	_ fileop.FileSystemSimpleBucket = (*FaultyFS)(nil)
+)
+
+// Faulty returns fsys with the faults of rules injected. When several rules
+// select a call, their latencies add up and the first error wins.
+// Probabilities are drawn from a generator with a fixed seed, so a sequence
+// of calls fails the same way in every run. Methods fsys lacks fail with
+// fileop.ErrUnsupported.
+func Faulty(fsys any, rules ...Rule) *FaultyFS {
+	return newFaultyFS(fsys, &faults{
+		rules:   rules,
+		matched: make([]int, len(rules)),
+		calls:   make(map[fileop.Op]int),
+		rand:    rand.New(rand.NewPCG(1, 2)),
+	})
+}
+
+func newFaultyFS(fsys any, fts *faults) *FaultyFS {
+	return &FaultyFS{
+		Forwarder: fileop.NewForwarder(fsys, func(fsys any) any {
+			return newFaultyFS(fsys, fts)
+		}),
+		faults: fts,
+	}
+}
+
+// Calls returns the number of calls of op so far, faulty or not.
+func (f *faults) Calls(op fileop.Op) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+type fault struct {
+	err        error
+	onClose    bool
+	shortRead  int
+	truncate   bool
+	truncateAt int64
+}
+
+func (f *faults) inject(op fileop.Op, name string) fault {
+	name = fileop.CleanPath(name)
+	var ft fault
+	var latency time.Duration
+
+	f.mu.Lock()
+	f.calls[op]++
+	for i, r := range f.rules {
+		if r.Op != "" && r.Op != op {
+			continue
+		}
+		if r.Path != "" {
+			if ok, _ := fileop.Match(r.Path, name); !ok {
+				continue
+			}
+		}
+		f.matched[i]++
+		if r.Nth > 0 && f.matched[i] != r.Nth {
+			continue
+		}
+		if r.Probability > 0 && f.rand.Float64() >= r.Probability {
+			continue
+		}
+		latency += r.Latency
+		if ft.err == nil && r.Err != nil {
+			ft.err = &fs.PathError{Op: string(op), Path: name, Err: r.Err}
+			ft.onClose = r.OnClose
+		}
+		if r.ShortRead > 0 {
+			ft.shortRead = r.ShortRead
+		}
+		if r.Truncate {
+			ft.truncate, ft.truncateAt = true, r.TruncateAt
+		}
+	}
+	f.mu.Unlock()
+
+	time.Sleep(latency)
+	return ft
+}
+
+func (f *FaultyFS) Open(name string) (io.ReadCloser, error) {
+	ft := f.inject(fileop.OpOpen, name)
+	if ft.err != nil && !ft.onClose {
+		return nil, ft.err
+	}
+	rd, err := f.Forwarder.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if ft.err == nil && ft.shortRead == 0 {
+		return rd, nil
+	}
+	return &faultyReader{ReadCloser: rd, shortRead: ft.shortRead, closeErr: ft.err}, nil
+}
+
+func (f *FaultyFS) Create(name string) (io.WriteCloser, error) {
+	ft := f.inject(fileop.OpCreate, name)
+	if ft.err != nil && !ft.onClose {
+		return nil, ft.err
+	}
+	wt, err := f.Forwarder.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if ft.err == nil && !ft.truncate {
+		return wt, nil
+	}
+	w := &faultyWriter{WriteCloser: wt, closeErr: ft.err, remaining: -1}
+	if ft.truncate {
+		w.remaining = max(ft.truncateAt, 0)
+	}
+	return w, nil
+}
+
+func (f *FaultyFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	if ft := f.inject(fileop.OpReaddir, dirname); ft.err != nil {
+		return nil, ft.err
+	}
+	return f.Forwarder.Readdir(dirname, n)
+}
+
+func (f *FaultyFS) Readdirnames(dirname string, n int) ([]string, error) {
+	if ft := f.inject(fileop.OpReaddirnames, dirname); ft.err != nil {
+		return nil, ft.err
+	}
+	return f.Forwarder.Readdirnames(dirname, n)
+}
+
+func (f *FaultyFS) Stat(name string) (fs.FileInfo, error) {
+	if ft := f.inject(fileop.OpStat, name); ft.err != nil {
+		return nil, ft.err
+	}
+	return f.Forwarder.Stat(name)
+}
+
+func (f *FaultyFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	if ft := f.inject(fileop.OpWalk, root); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Walk(root, walkFn)
+}
+
+func (f *FaultyFS) Mkdir(dirname string, perm fs.FileMode) error {
+	if ft := f.inject(fileop.OpMkdir, dirname); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Mkdir(dirname, perm)
+}
+
+func (f *FaultyFS) MkdirAll(dirname string, perm fs.FileMode) error {
+	if ft := f.inject(fileop.OpMkdirAll, dirname); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.MkdirAll(dirname, perm)
+}
+
+// Rename matches rules against oldPath.
+func (f *FaultyFS) Rename(oldPath, newPath string) error {
+	if ft := f.inject(fileop.OpRename, oldPath); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Rename(oldPath, newPath)
+}
+
+func (f *FaultyFS) Remove(name string) error {
+	if ft := f.inject(fileop.OpRemove, name); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Remove(name)
+}
+
+func (f *FaultyFS) RemoveAll(name string) error {
+	if ft := f.inject(fileop.OpRemoveAll, name); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.RemoveAll(name)
+}
+
+// RemoveBatch matches rules against every remote; faulty remotes are left
+// out of the forwarded batch.
+func (f *FaultyFS) RemoveBatch(remotes []string) []error {
+	errs := make([]error, len(remotes))
+	var forward []string
+	var index []int
+	failed := false
+	for i, remote := range remotes {
+		if ft := f.inject(fileop.OpRemoveBatch, remote); ft.err != nil {
+			errs[i], failed = ft.err, true
+			continue
+		}
+		forward = append(forward, remote)
+		index = append(index, i)
+	}
+	if len(forward) > 0 {
+		for j, err := range f.Forwarder.RemoveBatch(forward) {
+			if err != nil {
+				errs[index[j]], failed = err, true
+			}
+		}
+	}
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
+func (f *FaultyFS) Put(local, remote string) error {
+	if ft := f.inject(fileop.OpPut, remote); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Put(local, remote)
+}
+
+func (f *FaultyFS) PutStream(reader io.Reader, remote string) error {
+	if ft := f.inject(fileop.OpPutStream, remote); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.PutStream(reader, remote)
+}
+
+func (f *FaultyFS) PutStreamWithOptions(reader io.Reader, remote string, opts fileop.PutOptions) error {
+	if ft := f.inject(fileop.OpPutStream, remote); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.PutStreamWithOptions(reader, remote, opts)
+}
+
+func (f *FaultyFS) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	if ft := f.inject(fileop.OpPutStream, remote); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.PutStreamWithContentType(reader, remote, contentType)
+}
+
+func (f *FaultyFS) PutEmpty(remote string) error {
+	if ft := f.inject(fileop.OpPutEmpty, remote); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.PutEmpty(remote)
+}
+
+// Exist reports false for faulty calls.
+func (f *FaultyFS) Exist(remote string) bool {
+	if ft := f.inject(fileop.OpExist, remote); ft.err != nil {
+		return false
+	}
+	return f.Forwarder.Exist(remote)
+}
+
+func (f *FaultyFS) ExistE(remote string) (bool, error) {
+	if ft := f.inject(fileop.OpExist, remote); ft.err != nil {
+		return false, ft.err
+	}
+	return f.Forwarder.ExistE(remote)
+}
+
+// Copy matches rules against src.
+func (f *FaultyFS) Copy(src, dst string) error {
+	if ft := f.inject(fileop.OpCopy, src); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Copy(src, dst)
+}
+
+// Move matches rules against src.
+func (f *FaultyFS) Move(src, dst string) error {
+	if ft := f.inject(fileop.OpMove, src); ft.err != nil {
+		return ft.err
+	}
+	return f.Forwarder.Move(src, dst)
+}
+
+func (f *FaultyFS) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	if ft := f.inject(fileop.OpReaddir, dirname); ft.err != nil {
+		return nil, ft.err
+	}
+	return f.Forwarder.ReaddirPrefix(dirname, prefix, n)
+}
+
+func (f *FaultyFS) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	if ft := f.inject(fileop.OpReaddirnames, dirname); ft.err != nil {
+		return nil, ft.err
+	}
+	return f.Forwarder.ReaddirnamesPrefix(dirname, prefix, n)
+}
+
+func (f *FaultyFS) PresignGet(name string, ttl time.Duration) (string, error) {
+	if ft := f.inject(fileop.OpPresign, name); ft.err != nil {
+		return "", ft.err
+	}
+	return f.Forwarder.PresignGet(name, ttl)
+}
+
+func (f *FaultyFS) PresignPut(name string, ttl time.Duration) (string, error) {
+	if ft := f.inject(fileop.OpPresign, name); ft.err != nil {
+		return "", ft.err
+	}
+	return f.Forwarder.PresignPut(name, ttl)
+}
+
+type faultyReader struct {
+	io.ReadCloser
+	shortRead int
+	closeErr  error
+}
+
+func (r *faultyReader) Read(p []byte) (int, error) {
+	if r.shortRead > 0 && len(p) > r.shortRead {
+		p = p[:r.shortRead]
+	}
+	return r.ReadCloser.Read(p)
+}
+
+func (r *faultyReader) Close() error {
+	if err := r.ReadCloser.Close(); err != nil {
+		return err
+	}
+	return r.closeErr
+}
+
+// faultyWriter reports every write as complete but keeps only remaining
+// bytes, unless remaining is negative.
+type faultyWriter struct {
+	io.WriteCloser
+	remaining int64
+	closeErr  error
+}
+
+func (w *faultyWriter) Write(p []byte) (int, error) {
+	if w.remaining < 0 {
+		return w.WriteCloser.Write(p)
+	}
+	keep := p[:min(int64(len(p)), w.remaining)]
+	n, err := w.WriteCloser.Write(keep)
+	w.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return len(p), nil
+}
+
+func (w *faultyWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return w.closeErr
+}
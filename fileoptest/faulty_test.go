@@ -0,0 +1,159 @@
+package fileoptest
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/fileutil"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// maxReader records the largest Read result.
+type maxReader struct {
+	io.Reader
+	max int
+}
+
+func (r *maxReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.max = max(r.max, n)
+	return n, err
+}
+
+func TestFaulty(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	assert.NoError(fileutil.WriteFile(mfs, "/d/a.txt", strings.NewReader("0123456789")))
+
+	fsys := Faulty(mfs,
+		Rule{Op: fileop.OpStat, Nth: 2, Err: ErrFault},
+		Rule{Op: fileop.OpOpen, Path: "/d/*.txt", ShortRead: 3},
+		Rule{Op: fileop.OpCreate, Path: "/d/trunc.txt", Truncate: true, TruncateAt: 4},
+		Rule{Op: fileop.OpCreate, Path: "/d/close.txt", Err: fs.ErrPermission, OnClose: true},
+		Rule{Op: fileop.OpReaddirnames, Latency: 20 * time.Millisecond},
+		Rule{Op: fileop.OpRemove, Probability: 0.5, Err: ErrFault},
+	)
+
+	// Nth call
+	_, err = fsys.Stat("/d/a.txt")
+	assert.NoError(err)
+	_, err = fsys.Stat("/d/a.txt")
+	assert.ErrorIs(err, ErrFault)
+	assert.True(fileop.IsRetryable(err))
+	_, err = fsys.Stat("/d/a.txt")
+	assert.NoError(err)
+	assert.Equal(3, fsys.Calls(fileop.OpStat))
+
+	// short reads still return all data
+	rd, err := fsys.Open("/d/a.txt")
+	assert.NoError(err)
+	mr := &maxReader{Reader: rd}
+	b, err := io.ReadAll(mr)
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+	assert.Equal("0123456789", string(b))
+	assert.Equal(3, mr.max)
+
+	// truncated writes succeed silently
+	assert.NoError(fileutil.WriteFile(fsys, "/d/trunc.txt", strings.NewReader("0123456789")))
+	info, err := mfs.Stat("/d/trunc.txt")
+	assert.NoError(err)
+	assert.EqualValues(4, info.Size())
+
+	// errors on Close only
+	wt, err := fsys.Create("/d/close.txt")
+	assert.NoError(err)
+	_, err = wt.Write([]byte("x"))
+	assert.NoError(err)
+	assert.ErrorIs(wt.Close(), fs.ErrPermission)
+
+	// slow listings
+	start := time.Now()
+	_, err = fsys.Readdirnames("/d", 0)
+	assert.NoError(err)
+	assert.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+
+	// probability
+	var failed int
+	for range 100 {
+		if errors.Is(fsys.Remove("/d/missing.txt"), ErrFault) {
+			failed++
+		}
+	}
+	assert.InDelta(50, failed, 20)
+}
+
+func TestFaultyRetry(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	assert.NoError(fileutil.WriteFile(mfs, "/a.txt", strings.NewReader("a")))
+
+	faulty := Faulty(mfs, Rule{Op: fileop.OpOpen, Nth: 1, Err: ErrFault})
+	fsys, err := fileop.WithRetry[fileop.FileSystem](faulty, fileop.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	assert.NoError(err)
+	rd, err := fsys.Open("/a.txt")
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+	assert.Equal(2, faulty.Calls(fileop.OpOpen))
+}
+
+// copierFS is an afero file system with a rename based Copier.
+type copierFS struct {
+	*afero.Handler
+}
+
+func (c copierFS) Copy(src, dst string) error {
+	rd, err := c.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rd.Close() }()
+	return fileutil.WriteFile(c.Handler, dst, rd)
+}
+
+func (c copierFS) Move(src, dst string) error {
+	return c.Rename(src, dst)
+}
+
+func TestFaultyCapabilities(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	assert.NoError(fileutil.WriteFile(mfs, "/a.txt", strings.NewReader("a")))
+
+	// optional interfaces of the wrapped file system are detected with As
+	faulty := Faulty(copierFS{mfs}, Rule{Op: fileop.OpCopy, Nth: 1, Err: ErrFault})
+	c, ok := fileop.As[fileop.Copier](faulty)
+	assert.True(ok)
+	assert.ErrorIs(c.Copy("/a.txt", "/b.txt"), ErrFault)
+	assert.NoError(c.Copy("/a.txt", "/b.txt"))
+	assert.Equal(2, faulty.Calls(fileop.OpCopy))
+	_, ok = fileop.As[fileop.Copier](Faulty(mfs))
+	assert.False(ok)
+
+	// uploads of targets get faults too
+	target := Faulty(&filetarget.WrapFS{Target: mfs}, Rule{Op: fileop.OpPutStream, Path: "/up/*", Err: ErrFault})
+	assert.ErrorIs(target.PutStream(strings.NewReader("x"), "/up/x.txt"), ErrFault)
+	assert.NoError(target.PutStream(strings.NewReader("x"), "/x.txt"))
+	exist, err := target.ExistE("/x.txt")
+	assert.NoError(err)
+	assert.True(exist)
+	errs := target.RemoveBatch([]string{"/x.txt", "/missing.txt"})
+	assert.Nil(errs)
+}
@@ -0,0 +1,61 @@
+// Package fileoptest provides helpers for testing fileop implementations.
+package fileoptest
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+)
+
+// TestPathModel checks that a backend follows the fileop path model. It
+// uploads a few files below root through dst and lists them through src,
+// which are usually the same client.
+//
+// Listing must accept unclean directory names, return base names in
+// FileInfo.Name and Readdirnames, report directories with IsDir and
+// fs.ModeDir, and expose full paths through fileop.PathInfo.
+func TestPathModel(t *testing.T, src fileop.DirReader, dst fileop.ITargetUploader, root string) {
+	t.Helper()
+	assert := require.New(t)
+
+	root = fileop.CleanPath(root)
+	dir := path.Join(root, "a")
+	assert.NoError(dst.PutEmpty(path.Join(dir, "b.txt")))
+	assert.NoError(dst.PutStream(strings.NewReader("x"), path.Join(dir, "c", "d.txt")))
+
+	want := []string{"b.txt", "c"}
+	for _, name := range []string{dir, dir + "/", root + "//a", root + "/./a"} {
+		names, err := src.Readdirnames(name, 0)
+		assert.NoError(err, name)
+		sort.Strings(names)
+		assert.Equal(want, names, "Readdirnames(%q)", name)
+
+		infos, err := src.Readdir(name, 0)
+		assert.NoError(err, name)
+		assert.Len(infos, len(want), "Readdir(%q)", name)
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+		for i, info := range infos {
+			assert.Equal(want[i], info.Name(), "Readdir(%q)", name)
+			assert.Equal(info.Name() == "c", info.IsDir(), "IsDir of %q", info.Name())
+			assert.Equal(info.IsDir(), info.Mode().IsDir(), "Mode of %q", info.Name())
+
+			_, ok := info.(fileop.PathInfo)
+			assert.True(ok, "FileInfo of %q does not implement fileop.PathInfo", info.Name())
+			assert.Equal(path.Join(dir, info.Name()), fileop.InfoPath(name, info))
+		}
+	}
+
+	names, err := src.Readdirnames(path.Join(root, "missing"), 0)
+	if err != nil {
+		assert.True(errors.Is(err, fs.ErrNotExist), "listing a missing directory: %v", err)
+	} else {
+		assert.Empty(names, "listing a missing directory")
+	}
+}
@@ -0,0 +1,26 @@
+package fileoptest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestPathModelMemory(t *testing.T) {
+	t.Parallel()
+
+	mfs, err := afero.New(afero.Memory)
+	require.NoError(t, err)
+	TestPathModel(t, mfs, &filetarget.WrapFS{Target: mfs}, "/path_model")
+}
+
+func TestRemoveMemory(t *testing.T) {
+	t.Parallel()
+
+	mfs, err := afero.New(afero.Memory)
+	require.NoError(t, err)
+	TestRemove(t, &filetarget.WrapFS{Target: mfs}, "/remove")
+}
@@ -0,0 +1,40 @@
+package fileoptest
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+)
+
+// TestRemove checks the removal operations of a target below root. Removing
+// missing files must succeed, RemoveBatch must return nil when every file
+// was removed, and ExistE must report removed files without error.
+func TestRemove(t *testing.T, dst fileop.ITargetUploader, root string) {
+	t.Helper()
+	assert := require.New(t)
+
+	root = fileop.CleanPath(root)
+	names := []string{path.Join(root, "a.txt"), path.Join(root, "b.txt"), path.Join(root, "c", "d.txt")}
+	for _, name := range names {
+		assert.NoError(dst.PutEmpty(name))
+	}
+
+	exist, err := dst.ExistE(names[0])
+	assert.NoError(err)
+	assert.True(exist, "%s missing after PutEmpty", names[0])
+
+	assert.NoError(dst.Remove(names[0]))
+	exist, err = dst.ExistE(names[0])
+	assert.NoError(err, "ExistE of a missing file")
+	assert.False(exist, "%s exists after Remove", names[0])
+	assert.NoError(dst.Remove(names[0]), "removing a missing file")
+
+	assert.Nil(dst.RemoveBatch(names), "RemoveBatch with a missing file")
+	for _, name := range names {
+		assert.False(dst.Exist(name), "%s exists after RemoveBatch", name)
+	}
+	assert.Nil(dst.RemoveBatch(nil))
+}
@@ -49,7 +49,7 @@ func (fr *FileReader) free() {
 }
 
 // NewFileReader create *FileReader on any FileReaderInterface.
-// NOTE: you can call IsUnhandledFileReaderError judge errors can not solve by retry.
+// NOTE: you can call IsRetryable to judge whether errors can be solved by retry.
 func NewFileReader(fri FileReaderInterface, srcPath string, ct CompressType) (*FileReader, error) {
 	fr := frFree.Get().(*FileReader)
 	fr.Path = srcPath
@@ -70,6 +70,9 @@ func NewFileReader(fri FileReaderInterface, srcPath string, ct CompressType) (*F
 	return fr, nil
 }
 
+// IsUnhandledFileReaderError reports errors that can not be solved by retry.
+//
+// Deprecated: use IsRetryable, which also covers backend specific errors.
 func IsUnhandledFileReaderError(err error) bool {
 	return errors.Is(err, io.EOF) ||
 		errors.Is(err, os.ErrNotExist) ||
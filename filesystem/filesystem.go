@@ -6,14 +6,36 @@ import (
 	"github.com/marsgopher/fileop"
 	"github.com/marsgopher/fileop/integration/afero"
 	"github.com/marsgopher/fileop/integration/hdfs"
+	"github.com/marsgopher/fileop/integration/minio"
+	"github.com/marsgopher/fileop/integration/obs"
+	"github.com/marsgopher/fileop/integration/upyun"
 )
 
 type Config struct {
-	Mode string      `mapstructure:"mode"`
-	HDFS hdfs.Config `mapstructure:"hdfs"`
+	Mode  string       `mapstructure:"mode"`
+	HDFS  hdfs.Config  `mapstructure:"hdfs"`
+	OBS   obs.Config   `mapstructure:"obs"`
+	UPYUN upyun.Config `mapstructure:"upyun"`
+	MINIO minio.Config `mapstructure:"minio"`
+
+	// Mounts, when set, make New return a fileop.Router of the mounted
+	// file systems. The file system of Mode, if set, is mounted at "/".
+	Mounts []MountConfig `mapstructure:"mounts"`
+}
+
+// MountConfig configures the file system mounted at Path. The mount shows
+// the tree below Base, see fileop.Sub. Base is required for disk mounts so
+// that a mount can not reach the whole host file system.
+type MountConfig struct {
+	Path   string `mapstructure:"path"`
+	Base   string `mapstructure:"base"`
+	Config `mapstructure:",squash"`
 }
 
 func New(c Config) (fileop.FileSystemWithCloser, error) {
+	if len(c.Mounts) > 0 {
+		return newRouter(c)
+	}
 	switch c.Mode {
 	case "disk":
 		h, err := afero.New(afero.Disk)
@@ -21,13 +43,88 @@ func New(c Config) (fileop.FileSystemWithCloser, error) {
 			return nil, fmt.Errorf("new disk: %w", err)
 		}
 		return h, nil
+	case "memory":
+		h, err := afero.New(afero.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("new memory: %w", err)
+		}
+		return h, nil
 	case "hdfs":
 		h, err := hdfs.New(c.HDFS)
 		if err != nil {
 			return nil, fmt.Errorf("new hdfs: %w", err)
 		}
 		return h, nil
+	case "obs":
+		h, err := obs.New(c.OBS)
+		if err != nil {
+			return nil, fmt.Errorf("new obs: %w", err)
+		}
+		return &ObjectFS{Store: h}, nil
+	case "upyun":
+		h, err := upyun.New(c.UPYUN)
+		if err != nil {
+			return nil, fmt.Errorf("new upyun: %w", err)
+		}
+		return &ObjectFS{Store: h}, nil
+	case "minio", "s3":
+		h, err := minio.New(c.MINIO)
+		if err != nil {
+			return nil, fmt.Errorf("new minio: %w", err)
+		}
+		return &ObjectFS{Store: h}, nil
 	default:
 		return nil, fmt.Errorf("mode %s not support", c.Mode)
 	}
 }
+
+func newRouter(c Config) (fileop.FileSystemWithCloser, error) {
+	for _, m := range c.Mounts {
+		if m.Mode == "disk" && m.Base == "" {
+			return nil, fmt.Errorf("mount %s: disk mount without base", m.Path)
+		}
+	}
+	mounts := c.Mounts
+	if c.Mode != "" {
+		// the file system of Mode is the whole tree, as returned without mounts
+		root := c
+		root.Mounts = nil
+		mounts = append([]MountConfig{{Path: "/", Config: root}}, mounts...)
+	}
+
+	var fss []fileop.Mount
+	var opened []fileop.FileSystemWithCloser
+	closeAll := func() {
+		for _, fsys := range opened {
+			_ = fsys.Close()
+		}
+	}
+	for _, m := range mounts {
+		fsys, err := newMount(m)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("mount %s: %w", m.Path, err)
+		}
+		opened = append(opened, fsys)
+		fss = append(fss, fileop.Mount{Path: m.Path, FS: fsys})
+	}
+	r, err := fileop.NewRouter(fss...)
+	if err != nil {
+		closeAll()
+		return nil, fmt.Errorf("new router: %w", err)
+	}
+	return r, nil
+}
+
+func newMount(m MountConfig) (fileop.FileSystemWithCloser, error) {
+	fsys, err := New(m.Config)
+	if err != nil || m.Base == "" {
+		return fsys, err
+	}
+	sub, err := fileop.Sub(fsys, m.Base)
+	if err != nil {
+		_ = fsys.Close()
+		return nil, fmt.Errorf("sub %s: %w", m.Base, err)
+	}
+	return sub, nil
+}
@@ -0,0 +1,124 @@
+package filesystem
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/fileutil"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// memStore is an ObjectStore on afero Memory.
+type memStore struct {
+	*afero.Handler
+	*filetarget.WrapFS
+}
+
+func newMemStore(t *testing.T) *memStore {
+	h, err := afero.New(afero.Memory)
+	require.NoError(t, err)
+	return &memStore{Handler: h, WrapFS: &filetarget.WrapFS{Target: h}}
+}
+
+func (m *memStore) Stat(name string) (fs.FileInfo, error) { return m.Handler.Stat(name) }
+func (m *memStore) Remove(name string) error              { return m.WrapFS.Remove(name) }
+func (m *memStore) Close() error                          { return m.Handler.Close() }
+
+func (m *memStore) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	return m.Handler.Readdir(dirname, n)
+}
+
+func (m *memStore) Readdirnames(dirname string, n int) ([]string, error) {
+	return m.Handler.Readdirnames(dirname, n)
+}
+
+func readAll(t *testing.T, fsys fileop.Reader, name string) string {
+	rd, err := fsys.Open(name)
+	require.NoError(t, err)
+	defer func() { _ = rd.Close() }()
+	b, err := io.ReadAll(rd)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestNewMounts(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	fsys, err := New(Config{
+		Mode: "memory",
+		Mounts: []MountConfig{
+			{Path: "/tmp", Config: Config{Mode: "memory"}},
+		},
+	})
+	assert.NoError(err)
+	defer func() { _ = fsys.Close() }()
+	assert.NoError(fileutil.WriteFile(fsys, "/tmp/a.txt", strings.NewReader("a")))
+	assert.NoError(fileutil.WriteFile(fsys, "/b.txt", strings.NewReader("b")))
+	names, err := fsys.Readdirnames("/", 0)
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"b.txt", "tmp"}, names)
+
+	_, err = New(Config{Mounts: []MountConfig{{Path: "/x", Config: Config{Mode: "ftp"}}}})
+	assert.ErrorContains(err, "mount /x")
+}
+
+func TestNewMountsDiskBase(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base")
+	assert.NoError(os.Mkdir(base, 0755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "outside.txt"), []byte("x"), 0644))
+
+	_, err := New(Config{Mounts: []MountConfig{{Path: "/scratch", Config: Config{Mode: "disk"}}}})
+	assert.ErrorContains(err, "without base")
+
+	fsys, err := New(Config{
+		Mode:   "memory",
+		Mounts: []MountConfig{{Path: "/scratch", Base: base, Config: Config{Mode: "disk"}}},
+	})
+	assert.NoError(err)
+	defer func() { _ = fsys.Close() }()
+
+	assert.NoError(fileutil.WriteFile(fsys, "/scratch/a.txt", strings.NewReader("a")))
+	b, err := os.ReadFile(filepath.Join(base, "a.txt"))
+	assert.NoError(err)
+	assert.Equal("a", string(b))
+
+	// host paths resolve below base, and ".." does not leave it
+	_, err = fsys.Stat("/scratch" + filepath.ToSlash(filepath.Join(dir, "outside.txt")))
+	assert.ErrorIs(err, fs.ErrNotExist)
+	_, err = fsys.Stat("/scratch/../outside.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+	assert.Error(fsys.RemoveAll("/scratch"))
+	_, err = os.Stat(filepath.Join(dir, "outside.txt"))
+	assert.NoError(err)
+}
+
+func TestObjectFS(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	store := newMemStore(t)
+	fsys := &ObjectFS{Store: store}
+	assert.NoError(fileutil.WriteFile(fsys, "/d/a.txt", strings.NewReader("aaa")))
+	assert.Equal("aaa", readAll(t, store, "/d/a.txt"))
+
+	assert.NoError(fsys.Rename("/d/a.txt", "/e/a.txt"))
+	assert.Equal("aaa", readAll(t, fsys, "/e/a.txt"))
+	assert.False(store.Exist("/d/a.txt"))
+
+	assert.NoError(fsys.RemoveAll("/e"))
+	assert.False(store.Exist("/e/a.txt"))
+	assert.NoError(fsys.RemoveAll("/missing"))
+}
@@ -0,0 +1,113 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/marsgopher/fileop"
+)
+
+// ObjectStore is a backend without real directories, such as minio, obs
+// and upyun.
+type ObjectStore interface {
+	fileop.ISourceReader
+	fileop.ITargetUploader
+}
+
+// ObjectFS adapts an ObjectStore to fileop.FileSystem, e.g. to mount it in
+// a fileop.Router. Directories exist implicitly, so Mkdir and MkdirAll do
+// nothing, and Rename copies and removes the object.
+type ObjectFS struct {
+	Store ObjectStore
+}
+
+var _ fileop.FileSystemWithCloser = (*ObjectFS)(nil)
+
+func (o *ObjectFS) Open(name string) (io.ReadCloser, error) {
+	return o.Store.Open(name)
+}
+
+func (o *ObjectFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	return o.Store.Readdir(dirname, n)
+}
+
+func (o *ObjectFS) Readdirnames(dirname string, n int) ([]string, error) {
+	return o.Store.Readdirnames(dirname, n)
+}
+
+func (o *ObjectFS) Stat(name string) (fs.FileInfo, error) {
+	return o.Store.Stat(name)
+}
+
+func (o *ObjectFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	return fileop.Walk(o, root, walkFn)
+}
+
+func (o *ObjectFS) Mkdir(string, fs.FileMode) error {
+	return nil
+}
+
+func (o *ObjectFS) MkdirAll(string, fs.FileMode) error {
+	return nil
+}
+
+// Create uploads the written content with PutStream. The upload completes
+// when the writer is closed.
+func (o *ObjectFS) Create(name string) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	w := &objectWriter{PipeWriter: pw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		w.err = o.Store.PutStream(pr, name)
+		_ = pr.CloseWithError(w.err)
+	}()
+	return w, nil
+}
+
+func (o *ObjectFS) Rename(oldPath, newPath string) error {
+	return fileop.Move(o.Store, oldPath, newPath)
+}
+
+func (o *ObjectFS) Remove(name string) error {
+	return o.Store.Remove(name)
+}
+
+// RemoveAll removes name and every object below it.
+func (o *ObjectFS) RemoveAll(name string) error {
+	var remotes []string
+	err := o.Walk(name, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			remotes = append(remotes, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("list %s: %w", name, err)
+	}
+	return errors.Join(o.Store.RemoveBatch(remotes)...)
+}
+
+func (o *ObjectFS) Close() error {
+	return o.Store.Close()
+}
+
+type objectWriter struct {
+	*io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+func (w *objectWriter) Close() error {
+	_ = w.PipeWriter.Close()
+	<-w.done
+	return w.err
+}
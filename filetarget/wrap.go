@@ -1,8 +1,12 @@
 package filetarget
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
+	"path"
 
 	"github.com/marsgopher/fileop"
 	"github.com/marsgopher/fileop/integration/afero"
@@ -13,6 +17,7 @@ type WrapFS struct {
 	Target interface {
 		fileop.FileWriterInterface
 		fileop.Stater
+		fileop.Cleaner
 		io.Closer
 	}
 }
@@ -45,7 +50,7 @@ func (w *WrapFS) Put(local, remote string) error {
 	}()
 
 	if err := w.PutStream(reader, remote); err != nil {
-		return nil
+		return err
 	}
 	defer func() { reader = nil }()
 	if err := reader.Close(); err != nil {
@@ -75,6 +80,48 @@ func (w *WrapFS) PutStream(reader io.Reader, remote string) error {
 	return err
 }
 
+// PutStreamWithOptions uploads reader like PutStream. File systems store the
+// bytes verbatim, so ContentEncoding is kept as written and ContentType must
+// be empty or match the extension of remote. Other properties can not be
+// represented and fail with fileop.ErrUnsupported before anything is written.
+func (w *WrapFS) PutStreamWithOptions(reader io.Reader, remote string, opts fileop.PutOptions) error {
+	if err := checkPutOptions(remote, opts); err != nil {
+		return err
+	}
+	return w.PutStream(reader, remote)
+}
+
+func checkPutOptions(remote string, opts fileop.PutOptions) error {
+	var unsupported string
+	switch {
+	case opts.ContentType != "" && !sameMediaType(opts.ContentType, mime.TypeByExtension(path.Ext(remote))):
+		unsupported = "content type " + opts.ContentType
+	case opts.CacheControl != "":
+		unsupported = "cache control"
+	case opts.ContentDisposition != "":
+		unsupported = "content disposition"
+	case len(opts.Metadata) > 0:
+		unsupported = "metadata"
+	case opts.StorageClass != "":
+		unsupported = "storage class"
+	case opts.ACL != "":
+		unsupported = "acl"
+	default:
+		return nil
+	}
+	return &fs.PathError{Op: "put", Path: remote, Err: fmt.Errorf("%s: %w", unsupported, fileop.ErrUnsupported)}
+}
+
+// sameMediaType compares the media types of a and b, ignoring parameters.
+func sameMediaType(a, b string) bool {
+	ta, _, err := mime.ParseMediaType(a)
+	if err != nil {
+		return false
+	}
+	tb, _, err := mime.ParseMediaType(b)
+	return err == nil && ta == tb
+}
+
 func (w *WrapFS) PutEmpty(remote string) error {
 	writer, err := fileop.NewFileWriter(w.Target, remote, 0, fileop.NONE)
 	if err != nil {
@@ -93,7 +140,58 @@ func (w *WrapFS) PutFinish(remote string) error {
 	return writer.Close()
 }
 
+func (w *WrapFS) Stat(remote string) (fs.FileInfo, error) {
+	return w.Target.Stat(remote)
+}
+
+// Readdir lists dirname on Target, failing with fileop.ErrUnsupported when
+// Target can not be listed.
+func (w *WrapFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	if r, ok := fileop.As[fileop.DirReader](w.Target); ok {
+		return r.Readdir(dirname, n)
+	}
+	return nil, &fs.PathError{Op: "readdir", Path: dirname, Err: fileop.ErrUnsupported}
+}
+
+// Readdirnames is Readdir returning names only.
+func (w *WrapFS) Readdirnames(dirname string, n int) ([]string, error) {
+	if r, ok := fileop.As[fileop.DirReader](w.Target); ok {
+		return r.Readdirnames(dirname, n)
+	}
+	return nil, &fs.PathError{Op: "readdir", Path: dirname, Err: fileop.ErrUnsupported}
+}
+
+func (w *WrapFS) Remove(remote string) error {
+	if err := w.Target.Remove(remote); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", remote, err)
+	}
+	return nil
+}
+
+func (w *WrapFS) RemoveBatch(remotes []string) []error {
+	var errs []error
+	for i, remote := range remotes {
+		if err := w.Remove(remote); err != nil {
+			if errs == nil {
+				errs = make([]error, len(remotes))
+			}
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
 func (w *WrapFS) Exist(remote string) bool {
-	_, err := w.Target.Stat(remote)
-	return err == nil
+	ok, _ := w.ExistE(remote)
+	return ok
+}
+
+func (w *WrapFS) ExistE(remote string) (bool, error) {
+	if _, err := w.Target.Stat(remote); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("exist %s: %w", remote, err)
+	}
+	return true, nil
 }
@@ -0,0 +1,76 @@
+package filetarget
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// deniedFS fails every Stat with a permission error.
+type deniedFS struct {
+	*afero.Handler
+}
+
+func (deniedFS) Stat(name string) (fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrPermission}
+}
+
+func TestWrapFSExistE(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	w := &WrapFS{Target: mfs}
+	assert.NoError(w.PutEmpty("/a.txt"))
+
+	exist, err := w.ExistE("/a.txt")
+	assert.NoError(err)
+	assert.True(exist)
+
+	exist, err = w.ExistE("/missing.txt")
+	assert.NoError(err)
+	assert.False(exist)
+
+	w = &WrapFS{Target: deniedFS{mfs}}
+	exist, err = w.ExistE("/a.txt")
+	assert.ErrorIs(err, fs.ErrPermission)
+	assert.False(exist)
+	assert.False(w.Exist("/a.txt"))
+}
+
+func TestWrapFSPutStreamWithOptions(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	w := &WrapFS{Target: mfs}
+
+	opts := fileop.PutOptions{ContentType: "text/plain; charset=utf-8", ContentEncoding: "gzip"}
+	assert.NoError(w.PutStreamWithOptions(strings.NewReader("a"), "/a.txt", opts))
+	exist, err := w.ExistE("/a.txt")
+	assert.NoError(err)
+	assert.True(exist)
+
+	// properties a file system can not keep fail without writing
+	for _, opts := range []fileop.PutOptions{
+		{ContentType: "application/json"},
+		{CacheControl: "no-cache"},
+		{ContentDisposition: "attachment"},
+		{Metadata: map[string]string{"k": "v"}},
+		{StorageClass: "COLD"},
+		{ACL: "public-read"},
+	} {
+		err := w.PutStreamWithOptions(strings.NewReader("b"), "/b.txt", opts)
+		assert.ErrorIs(err, fileop.ErrUnsupported, "%+v", opts)
+	}
+	exist, err = w.ExistE("/b.txt")
+	assert.NoError(err)
+	assert.False(exist)
+}
@@ -0,0 +1,258 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/marsgopher/common/concurrency"
+	"github.com/marsgopher/fileop"
+)
+
+// SkipMode decides which source files CopyTree leaves alone.
+type SkipMode int
+
+const (
+	SkipNone         SkipMode = iota // copy every file
+	SkipExisting                     // skip when the destination exists
+	SkipSameSize                     // skip when the destination has the same size
+	SkipSameChecksum                 // skip when ETags match, or sizes when an ETag is unknown
+)
+
+// CopyOptions configures CopyTree.
+type CopyOptions struct {
+	SrcDir  string // source directory to copy recursively
+	DstDir  string // destination directory receiving the relative paths
+	Workers int    // concurrent uploads, at least 1
+
+	// Skip decides which files are left alone. Size and checksum
+	// comparisons are meaningless when recompressing.
+	Skip SkipMode
+
+	// SrcCompress and DstCompress recompress files when they differ.
+	SrcCompress fileop.CompressType
+	DstCompress fileop.CompressType
+
+	// Filter, when set, selects the source files to copy.
+	Filter func(name string, info fs.FileInfo) bool
+}
+
+// CopyReport summarizes a CopyTree run. Paths are source paths.
+type CopyReport struct {
+	Copied  []string
+	Skipped []string
+	Failed  map[string]error
+	Bytes   int64 // bytes read from the source for copied files
+}
+
+// CopyTree copies every file below opts.SrcDir on src to opts.DstDir on
+// dst. Failures of single files are collected in the report and do not stop
+// the copy; the returned error is only set when listing src fails.
+func CopyTree(src fileop.ISourceReader, dst fileop.ITargetUploader, opts CopyOptions) (*CopyReport, error) {
+	c := &treeCopier{
+		src:    src,
+		dst:    dst,
+		opts:   opts,
+		report: &CopyReport{Failed: make(map[string]error)},
+	}
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	wg := concurrency.NewSemaErrGroup(workers)
+	listErr := listTree(src, fileop.CleanPath(opts.SrcDir), func(name string, info fs.FileInfo) {
+		if opts.Filter != nil && !opts.Filter(name, info) {
+			return
+		}
+		wg.Do(func() error {
+			c.copyFile(name, info)
+			return nil
+		})
+	})
+	_ = wg.Wait()
+
+	sort.Strings(c.report.Copied)
+	sort.Strings(c.report.Skipped)
+	if listErr != nil {
+		return c.report, fmt.Errorf("list %s: %w", opts.SrcDir, listErr)
+	}
+	return c.report, nil
+}
+
+// listTree calls fn for every file below dir.
+func listTree(src fileop.DirReader, dir string, fn func(name string, info fs.FileInfo)) error {
+	infos, err := src.Readdir(dir, 0)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := fileop.InfoPath(dir, info)
+		if info.IsDir() {
+			if err := listTree(src, name, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		fn(name, info)
+	}
+	return nil
+}
+
+// relPath returns name relative to its ancestor root like filepath.Rel.
+// Roots of "", "." and "/" are the top of the tree, whose listings may
+// return names with or without leading slash.
+func relPath(root, name string) string {
+	root = strings.TrimPrefix(fileop.CleanPath(root), "/")
+	name = strings.TrimPrefix(fileop.CleanPath(name), "/")
+	switch {
+	case root == "" || root == ".":
+		if name == "." {
+			return ""
+		}
+		return name
+	case name == root:
+		return ""
+	case strings.HasPrefix(name, root+"/"):
+		return name[len(root)+1:]
+	}
+	return name
+}
+
+type treeCopier struct {
+	src  fileop.ISourceReader
+	dst  fileop.ITargetUploader
+	opts CopyOptions
+
+	mu     sync.Mutex
+	report *CopyReport
+}
+
+func (c *treeCopier) copyFile(name string, info fs.FileInfo) {
+	target := path.Join(c.opts.DstDir, relPath(c.opts.SrcDir, name))
+
+	skip, err := c.skip(info, target)
+	if err != nil {
+		c.fail(name, err)
+		return
+	}
+	if skip {
+		c.mu.Lock()
+		c.report.Skipped = append(c.report.Skipped, name)
+		c.mu.Unlock()
+		return
+	}
+
+	n, err := upload(c.src, c.dst, name, target, c.opts.SrcCompress, c.opts.DstCompress)
+	if err != nil {
+		c.fail(name, err)
+		return
+	}
+	c.mu.Lock()
+	c.report.Copied = append(c.report.Copied, name)
+	c.report.Bytes += n
+	c.mu.Unlock()
+}
+
+func (c *treeCopier) fail(name string, err error) {
+	c.mu.Lock()
+	c.report.Failed[name] = err
+	c.mu.Unlock()
+}
+
+func (c *treeCopier) skip(info fs.FileInfo, target string) (bool, error) {
+	switch c.opts.Skip {
+	case SkipExisting:
+		exist, err := c.dst.ExistE(target)
+		if err != nil {
+			return false, err
+		}
+		return exist, nil
+	case SkipSameSize, SkipSameChecksum:
+		dstInfo, err := c.dst.Stat(target)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return false, nil
+			}
+			return false, fmt.Errorf("stat %s: %w", target, err)
+		}
+		if c.opts.Skip == SkipSameChecksum {
+			srcTag, dstTag := normalizeETag(fileop.Attrs(info).ETag), normalizeETag(fileop.Attrs(dstInfo).ETag)
+			if srcTag != "" && dstTag != "" {
+				return srcTag == dstTag, nil
+			}
+		}
+		return info.Size() == dstInfo.Size(), nil
+	default:
+		return false, nil
+	}
+}
+
+// upload copies name on src to target on dst, recompressing from srcCT to
+// dstCT, and returns the number of bytes read.
+func upload(src fileop.Reader, dst fileop.ITargetUploader, name, target string, srcCT, dstCT fileop.CompressType) (int64, error) {
+	rd, err := src.Open(name)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", name, err)
+	}
+	defer func() { _ = rd.Close() }()
+	counter := &countingReader{Reader: rd}
+
+	if srcCT == dstCT {
+		if err := dst.PutStream(counter, target); err != nil {
+			return 0, err
+		}
+		return counter.n, nil
+	}
+
+	decompressed, err := fileop.NewCompressReader(counter, srcCT)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = decompressed.Close() }()
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wt, err := fileop.NewCompressWriter(pw, dstCT)
+		if err != nil {
+			_ = pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(wt, decompressed); err != nil {
+			_ = wt.Close()
+			_ = pw.CloseWithError(err)
+			return
+		}
+		_ = pw.CloseWithError(wt.Close())
+	}()
+	err = dst.PutStream(pr, target)
+	// unblock the compressor if the upload stopped reading early
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	if err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func normalizeETag(tag string) string {
+	return strings.ToLower(strings.Trim(tag, `"`))
+}
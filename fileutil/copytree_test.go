@@ -0,0 +1,127 @@
+package fileutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestCopyTree(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	src, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dstFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dst := &filetarget.WrapFS{Target: dstFS}
+
+	for _, name := range []string{"/src/a.txt", "/src/d/b.txt", "/src/d/e/c.txt"} {
+		assert.NoError(WriteFile(src, name, strings.NewReader(name)))
+	}
+	assert.NoError(WriteFile(dstFS, "/dst/a.txt", strings.NewReader("old")))
+
+	report, err := CopyTree(src, dst, CopyOptions{
+		SrcDir:  "/src",
+		DstDir:  "/dst",
+		Workers: 2,
+		Skip:    SkipExisting,
+	})
+	assert.NoError(err)
+	assert.Equal([]string{"/src/d/b.txt", "/src/d/e/c.txt"}, report.Copied)
+	assert.Equal([]string{"/src/a.txt"}, report.Skipped)
+	assert.Empty(report.Failed)
+	assert.Equal(int64(len("/src/d/b.txt")+len("/src/d/e/c.txt")), report.Bytes)
+
+	content, err := ReadFile(dstFS, "/dst/d/e/c.txt")
+	assert.NoError(err)
+	assert.Equal("/src/d/e/c.txt", string(content))
+
+	// same size skips nothing as "old" differs from "/src/a.txt"
+	report, err = CopyTree(src, dst, CopyOptions{SrcDir: "/src", DstDir: "/dst", Skip: SkipSameSize})
+	assert.NoError(err)
+	assert.Equal([]string{"/src/a.txt"}, report.Copied)
+	assert.Len(report.Skipped, 2)
+}
+
+func TestCopyTreeRecompress(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	src, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dstFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+
+	assert.NoError(WriteFile(src, "/src/a.txt", strings.NewReader("hello")))
+
+	report, err := CopyTree(src, &filetarget.WrapFS{Target: dstFS}, CopyOptions{
+		SrcDir:      "/src",
+		DstDir:      "/dst",
+		DstCompress: fileop.GZIP,
+	})
+	assert.NoError(err)
+	assert.Len(report.Copied, 1)
+
+	content, err := ReadFile(dstFS, "/dst/a.txt")
+	assert.NoError(err)
+	rd, err := fileop.NewCompressReader(bytes.NewReader(content), fileop.GZIP)
+	assert.NoError(err)
+	plain, err := io.ReadAll(rd)
+	assert.NoError(err)
+	assert.Equal("hello", string(plain))
+}
+
+// relativeFS lists relative names for relative directories, like a disk
+// file system relative to the working directory.
+type relativeFS struct {
+	*afero.Handler
+}
+
+func (r relativeFS) Open(name string) (io.ReadCloser, error) {
+	return r.Handler.Open(path.Join("/", name))
+}
+
+func (r relativeFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	infos, err := r.Handler.Readdir(path.Join("/", dirname), n)
+	for i, info := range infos {
+		infos[i] = fileop.WithPath(info, path.Join(dirname, info.Name()))
+	}
+	return infos, err
+}
+
+func TestCopyTreeDotRoot(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dstFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	for _, name := range []string{"/.hidden", "/d/.env"} {
+		assert.NoError(WriteFile(mfs, name, strings.NewReader(name)))
+	}
+	src := relativeFS{mfs}
+
+	for i, srcDir := range []string{".", "", "/"} {
+		dstDir := fmt.Sprintf("/dst%d", i)
+		report, err := CopyTree(src, &filetarget.WrapFS{Target: dstFS}, CopyOptions{SrcDir: srcDir, DstDir: dstDir})
+		assert.NoError(err)
+		assert.Len(report.Copied, 2, srcDir)
+		for _, name := range []string{"/.hidden", "/d/.env"} {
+			content, err := ReadFile(dstFS, dstDir+name)
+			assert.NoError(err, srcDir)
+			assert.Equal(name, string(content))
+		}
+	}
+}
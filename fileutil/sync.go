@@ -0,0 +1,241 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/marsgopher/common/concurrency"
+	"github.com/marsgopher/fileop"
+)
+
+// SyncOp is the kind of a SyncAction.
+type SyncOp int
+
+const (
+	SyncUpload SyncOp = iota // upload the source file
+	SyncDelete               // delete the extraneous destination file
+)
+
+func (op SyncOp) String() string {
+	switch op {
+	case SyncUpload:
+		return "upload"
+	case SyncDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// SyncAction is a single step planned by Sync.
+type SyncAction struct {
+	Op     SyncOp
+	Path   string // relative to SrcDir and DstDir
+	Reason string // "missing", "size", "checksum", "modtime" or "extraneous"
+}
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	SrcDir  string
+	DstDir  string
+	Workers int // concurrent uploads and deletions, at least 1
+
+	// Files with equal size are considered up to date unless one of the
+	// following detects a difference.
+	CompareChecksum bool // ETags differ, when both are known
+	CompareModTime  bool // source is newer than destination
+
+	// Delete removes destination files missing on the source. It requires
+	// dst to implement fileop.DirReader.
+	Delete bool
+
+	// Include and Exclude filter relative paths with fileop.Match patterns.
+	// A pattern without slash matches the base name. Excluded files are
+	// neither uploaded nor deleted.
+	Include []string
+	Exclude []string
+
+	// DryRun only plans the actions.
+	DryRun bool
+}
+
+// SyncReport lists the actions planned, or executed unless DryRun is set.
+type SyncReport struct {
+	Actions []SyncAction
+	Failed  map[string]error // by relative path
+	Bytes   int64            // bytes read from the source for uploads
+}
+
+// Sync makes the tree below opts.DstDir on dst match opts.SrcDir on src.
+// The destination is listed once when dst implements fileop.DirReader,
+// detected with fileop.As; when it does not, or listing fails with
+// fileop.ErrUnsupported, every file is checked with Stat instead. Delete
+// needs the listing. Failures of single files are collected in the report.
+func Sync(src fileop.ISourceReader, dst fileop.ITargetUploader, opts SyncOptions) (*SyncReport, error) {
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := fileop.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+	lister, _ := fileop.As[fileop.DirReader](dst)
+	if opts.Delete && lister == nil {
+		return nil, fmt.Errorf("delete: %w", fileop.ErrUnsupported)
+	}
+
+	srcRoot := fileop.CleanPath(opts.SrcDir)
+	srcFiles, err := listFiles(src, srcRoot, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", opts.SrcDir, err)
+	}
+
+	var dstFiles map[string]fs.FileInfo
+	if lister != nil {
+		dstFiles, err = listFiles(lister, fileop.CleanPath(opts.DstDir), opts)
+		switch {
+		case errors.Is(err, fileop.ErrUnsupported) && !opts.Delete:
+		case errors.Is(err, fileop.ErrUnsupported):
+			return nil, fmt.Errorf("delete: %w", err)
+		case err != nil:
+			return nil, fmt.Errorf("list %s: %w", opts.DstDir, err)
+		}
+	}
+
+	report := &SyncReport{Failed: make(map[string]error)}
+	for _, rel := range sortedKeys(srcFiles) {
+		var dstInfo fs.FileInfo
+		if dstFiles != nil {
+			dstInfo = dstFiles[rel]
+		} else if dstInfo, err = statTarget(dst, path.Join(opts.DstDir, rel)); err != nil {
+			report.Failed[rel] = err
+			continue
+		}
+		if reason := syncReason(srcFiles[rel], dstInfo, opts); reason != "" {
+			report.Actions = append(report.Actions, SyncAction{Op: SyncUpload, Path: rel, Reason: reason})
+		}
+	}
+	if opts.Delete {
+		for _, rel := range sortedKeys(dstFiles) {
+			if _, ok := srcFiles[rel]; !ok {
+				report.Actions = append(report.Actions, SyncAction{Op: SyncDelete, Path: rel, Reason: "extraneous"})
+			}
+		}
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var mu sync.Mutex
+	wg := concurrency.NewSemaErrGroup(workers)
+	for _, action := range report.Actions {
+		wg.Do(func() error {
+			var n int64
+			var err error
+			target := path.Join(opts.DstDir, action.Path)
+			switch action.Op {
+			case SyncUpload:
+				n, err = upload(src, dst, path.Join(srcRoot, action.Path), target, fileop.NONE, fileop.NONE)
+			case SyncDelete:
+				err = dst.Remove(target)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Failed[action.Path] = err
+			}
+			report.Bytes += n
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	return report, nil
+}
+
+// listFiles lists the files below root by relative path, applying the
+// include and exclude patterns. A missing root is empty.
+func listFiles(fsys fileop.DirReader, root string, opts SyncOptions) (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	err := listTree(fsys, root, func(name string, info fs.FileInfo) {
+		rel := relPath(root, name)
+		if syncSelected(rel, opts) {
+			files[rel] = info
+		}
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// statTarget returns the FileInfo of name on dst, or nil if it does not
+// exist.
+func statTarget(dst fileop.ITargetUploader, name string) (fs.FileInfo, error) {
+	info, err := dst.Stat(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("stat %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// syncReason returns why src must be uploaded over dst, or "" if dst is up
+// to date.
+func syncReason(src, dst fs.FileInfo, opts SyncOptions) string {
+	if dst == nil {
+		return "missing"
+	}
+	if src.Size() != dst.Size() {
+		return "size"
+	}
+	if opts.CompareChecksum {
+		srcTag, dstTag := normalizeETag(fileop.Attrs(src).ETag), normalizeETag(fileop.Attrs(dst).ETag)
+		if srcTag != "" && dstTag != "" && srcTag != dstTag {
+			return "checksum"
+		}
+	}
+	if opts.CompareModTime && src.ModTime().After(dst.ModTime()) {
+		return "modtime"
+	}
+	return ""
+}
+
+func syncSelected(rel string, opts SyncOptions) bool {
+	if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+		return false
+	}
+	return !matchAny(opts.Exclude, rel)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if matched, _ := fileop.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]fs.FileInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,114 @@
+package fileutil
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// unlistable hides the listing methods of a file system.
+type unlistable struct {
+	target
+}
+
+type target interface {
+	fileop.FileWriterInterface
+	fileop.Stater
+	fileop.Cleaner
+	io.Closer
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	src, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dstFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dst := &filetarget.WrapFS{Target: dstFS}
+
+	for name, content := range map[string]string{
+		"/src/same.txt":    "same",
+		"/src/changed.txt": "changed",
+		"/src/new/a.txt":   "new",
+		"/src/skip.log":    "log",
+	} {
+		assert.NoError(WriteFile(src, name, strings.NewReader(content)))
+	}
+	for name, content := range map[string]string{
+		"/dst/same.txt":    "same",
+		"/dst/changed.txt": "old",
+		"/dst/extra.txt":   "extra",
+		"/dst/keep.log":    "log",
+	} {
+		assert.NoError(WriteFile(dstFS, name, strings.NewReader(content)))
+	}
+
+	opts := SyncOptions{
+		SrcDir:  "/src",
+		DstDir:  "/dst",
+		Workers: 2,
+		Delete:  true,
+		Exclude: []string{"*.log"},
+		DryRun:  true,
+	}
+	want := []SyncAction{
+		{Op: SyncUpload, Path: "changed.txt", Reason: "size"},
+		{Op: SyncUpload, Path: "new/a.txt", Reason: "missing"},
+		{Op: SyncDelete, Path: "extra.txt", Reason: "extraneous"},
+	}
+
+	report, err := Sync(src, dst, opts)
+	assert.NoError(err)
+	assert.Equal(want, report.Actions)
+	assert.True(dst.Exist("/dst/extra.txt"), "dry run must not delete")
+
+	opts.DryRun = false
+	report, err = Sync(src, dst, opts)
+	assert.NoError(err)
+	assert.Equal(want, report.Actions)
+	assert.Empty(report.Failed)
+
+	assert.False(dst.Exist("/dst/extra.txt"))
+	assert.True(dst.Exist("/dst/keep.log"))
+	content, err := ReadFile(dstFS, "/dst/changed.txt")
+	assert.NoError(err)
+	assert.Equal("changed", string(content))
+
+	report, err = Sync(src, dst, opts)
+	assert.NoError(err)
+	assert.Empty(report.Actions)
+}
+
+func TestSyncStat(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	src, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	dstFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	assert.NoError(WriteFile(src, "/src/same.txt", strings.NewReader("same")))
+	assert.NoError(WriteFile(src, "/src/new.txt", strings.NewReader("new")))
+	assert.NoError(WriteFile(dstFS, "/dst/same.txt", strings.NewReader("same")))
+
+	// the middleware implements DirReader, the target below can not list
+	dst, err := fileop.WithRetry[fileop.ITargetUploader](&filetarget.WrapFS{Target: unlistable{dstFS}}, fileop.RetryPolicy{BaseDelay: time.Millisecond})
+	assert.NoError(err)
+	report, err := Sync(src, dst, SyncOptions{SrcDir: "/src", DstDir: "/dst"})
+	assert.NoError(err)
+	assert.Equal([]SyncAction{{Op: SyncUpload, Path: "new.txt", Reason: "missing"}}, report.Actions)
+	assert.Empty(report.Failed)
+	assert.True(dst.Exist("/dst/new.txt"))
+
+	_, err = Sync(src, dst, SyncOptions{SrcDir: "/src", DstDir: "/dst", Delete: true})
+	assert.ErrorIs(err, fileop.ErrUnsupported)
+}
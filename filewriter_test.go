@@ -1,4 +1,4 @@
-package fileop
+package fileop_test
 
 import (
 	"fmt"
@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/marsgopher/fileop"
 	"github.com/marsgopher/fileop/integration/afero"
 )
 
@@ -38,7 +39,7 @@ func TestListDirAfterConcurrencyFileWrite(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
-			wt, err := NewFileWriter(mfs, fp, 0, NONE)
+			wt, err := fileop.NewFileWriter(mfs, fp, 0, fileop.NONE)
 			assert.NoError(err)
 			defer func() {
 				assert.NoError(wt.Close())
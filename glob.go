@@ -0,0 +1,263 @@
+package fileop
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// PrefixLister is implemented by backends that can restrict a directory
+// listing to entries whose base name starts with prefix, such as object
+// stores where the prefix is sent along with the list request.
+type PrefixLister interface {
+	ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error)
+	ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error)
+}
+
+// Glob returns the names of all files matching pattern, or nil if there is
+// no matching file. Paths are slash-separated and each segment follows the
+// syntax of path.Match; a segment consisting of "**" matches zero or more
+// directories, and a trailing "**" the directory itself and every file and
+// directory below it, like Match.
+//
+// Leading segments without meta characters are never listed, and when fsys
+// implements PrefixLister, detected with As, the literal prefix of a
+// segment (e.g. "2021-12-" in "2021-12-*") is pushed down to the listing
+// call. Directories that do not exist are ignored; any other listing error
+// is returned. Names matched more than once, e.g. by several "**", are
+// returned once.
+//
+// The only possible returned error besides listing errors is
+// path.ErrBadPattern, when pattern is malformed.
+func Glob(fsys DirReader, pattern string) ([]string, error) {
+	segs := strings.Split(pattern, "/")
+	for _, seg := range segs {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// collapse repeated "**" which would otherwise yield duplicates
+	compact := segs[:0]
+	for i, seg := range segs {
+		if seg == "**" && i > 0 && segs[i-1] == "**" {
+			continue
+		}
+		compact = append(compact, seg)
+	}
+	segs = compact
+
+	var dir string
+	switch {
+	case len(segs) > 1 && segs[0] == "":
+		dir, segs = "/", segs[1:]
+	default:
+		dir = "."
+	}
+
+	g := &globber{fsys: fsys, seen: make(map[string]bool)}
+	if err := g.glob(dir, segs); err != nil {
+		return nil, err
+	}
+	return g.matches, nil
+}
+
+// Match reports whether the slash-separated name matches pattern, using the
+// same syntax as Glob. The only possible returned error is
+// path.ErrBadPattern, when pattern is malformed.
+func Match(pattern, name string) (bool, error) {
+	segs := strings.Split(pattern, "/")
+	for _, seg := range segs {
+		if _, err := path.Match(seg, ""); err != nil {
+			return false, err
+		}
+	}
+	return matchSegments(segs, strings.Split(name, "/")), nil
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+type globber struct {
+	fsys    DirReader
+	matches []string
+	seen    map[string]bool
+}
+
+// add records a match unless it was found before.
+func (g *globber) add(name string) {
+	if !g.seen[name] {
+		g.seen[name] = true
+		g.matches = append(g.matches, name)
+	}
+}
+
+func (g *globber) glob(dir string, segs []string) error {
+	if len(segs) == 0 {
+		g.add(dir)
+		return nil
+	}
+
+	seg, rest := segs[0], segs[1:]
+	last := len(rest) == 0
+
+	if seg == "**" {
+		infos, found, err := g.list(dir, "")
+		if err != nil || !found {
+			return err
+		}
+		// "**" matching zero directories
+		if err := g.glob(dir, rest); err != nil {
+			return err
+		}
+		for _, info := range infos {
+			name := path.Join(dir, globBase(info.Name()))
+			switch {
+			case info.IsDir():
+				if err := g.glob(name, segs); err != nil {
+					return err
+				}
+			case last:
+				g.add(name)
+			}
+		}
+		return nil
+	}
+
+	if !hasMeta(seg) {
+		if !last {
+			return g.glob(path.Join(dir, seg), rest)
+		}
+		if st, ok := As[Stater](g.fsys); ok {
+			if _, err := st.Stat(path.Join(dir, seg)); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			g.add(path.Join(dir, seg))
+			return nil
+		}
+	}
+
+	prefix := literalPrefix(seg)
+	if last {
+		names, err := g.readdirnames(dir, prefix)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if matched, _ := path.Match(seg, name); matched {
+				g.add(path.Join(dir, name))
+			}
+		}
+		return nil
+	}
+
+	infos, err := g.readdir(dir, prefix)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		name := globBase(info.Name())
+		if matched, _ := path.Match(seg, name); matched {
+			if err := g.glob(path.Join(dir, name), rest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readdir lists dir sorted by name, treating a missing directory as empty.
+func (g *globber) readdir(dir, prefix string) ([]fs.FileInfo, error) {
+	infos, _, err := g.list(dir, prefix)
+	return infos, err
+}
+
+// list is readdir also reporting whether dir was found.
+func (g *globber) list(dir, prefix string) ([]fs.FileInfo, bool, error) {
+	var infos []fs.FileInfo
+	var err error
+	if pl, ok := As[PrefixLister](g.fsys); ok {
+		infos, err = pl.ReaddirPrefix(dir, prefix, 0)
+	} else {
+		infos, err = g.fsys.Readdir(dir, 0)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return globBase(infos[i].Name()) < globBase(infos[j].Name())
+	})
+	return infos, true, nil
+}
+
+// readdirnames lists the base names in dir sorted, treating a missing
+// directory as empty.
+func (g *globber) readdirnames(dir, prefix string) ([]string, error) {
+	var names []string
+	var err error
+	if pl, ok := As[PrefixLister](g.fsys); ok {
+		names, err = pl.ReaddirnamesPrefix(dir, prefix, 0)
+	} else {
+		names, err = g.fsys.Readdirnames(dir, 0)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = globBase(name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// globBase reduces a listed name to its base name. Object stores may return
+// the full key, and directories with a trailing slash.
+func globBase(name string) string {
+	return path.Base(strings.TrimSuffix(name, "/"))
+}
+
+// hasMeta reports whether seg contains any of the magic characters
+// recognized by path.Match.
+func hasMeta(seg string) bool {
+	return strings.ContainsAny(seg, `*?[\`)
+}
+
+// literalPrefix returns the leading part of seg before any meta character.
+func literalPrefix(seg string) string {
+	if i := strings.IndexAny(seg, `*?[\`); i >= 0 {
+		return seg[:i]
+	}
+	return seg
+}
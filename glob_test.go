@@ -0,0 +1,159 @@
+package fileop_test
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	for _, fp := range []string{
+		"/migu/2021-12-03/a.com/02/05/x.gz",
+		"/migu/2021-12-04/a.com/02/05/x.gz",
+		"/migu/2021-12-04/a.com/02/05/x.txt",
+		"/migu/2021-12-04/b.com/02/06/y.gz",
+		"/migu/2021-12-04/b.com/03/06/y.gz",
+		"/migu/2021-11-30/a.com/02/05/x.gz",
+		"/migu/2021-12-04/deep/a/b/c/z.gz",
+		"/x/x/f",
+	} {
+		wt, err := fileop.NewFileWriter(mfs, fp, 0, fileop.NONE)
+		assert.NoError(err)
+		assert.NoError(wt.Close())
+	}
+
+	for _, c := range []struct {
+		pattern string
+		want    []string
+	}{
+		{"/migu/2021-12-*/*/02/*/*.gz", []string{
+			"/migu/2021-12-03/a.com/02/05/x.gz",
+			"/migu/2021-12-04/a.com/02/05/x.gz",
+			"/migu/2021-12-04/b.com/02/06/y.gz",
+		}},
+		{"/migu/2021-1?-0[3]/*/*/*/x.*", []string{
+			"/migu/2021-12-03/a.com/02/05/x.gz",
+		}},
+		{"/migu/2021-12-04/**/*.gz", []string{
+			"/migu/2021-12-04/a.com/02/05/x.gz",
+			"/migu/2021-12-04/b.com/02/06/y.gz",
+			"/migu/2021-12-04/b.com/03/06/y.gz",
+			"/migu/2021-12-04/deep/a/b/c/z.gz",
+		}},
+		{"/migu/**/c/*", []string{
+			"/migu/2021-12-04/deep/a/b/c/z.gz",
+		}},
+		{"/migu/2021-12-04/a.com/02/05/x.txt", []string{
+			"/migu/2021-12-04/a.com/02/05/x.txt",
+		}},
+		{"/migu/2021-12-04/deep/**", []string{
+			"/migu/2021-12-04/deep",
+			"/migu/2021-12-04/deep/a",
+			"/migu/2021-12-04/deep/a/b",
+			"/migu/2021-12-04/deep/a/b/c",
+			"/migu/2021-12-04/deep/a/b/c/z.gz",
+		}},
+		// every "**" can match x/x/f, each name is returned once
+		{"/**/x/**", []string{"/x", "/x/x", "/x/x/f"}},
+		{"/migu/2022-*/*", nil},
+		{"/missing/*", nil},
+	} {
+		got, err := fileop.Glob(mfs, c.pattern)
+		assert.NoError(err, c.pattern)
+		assert.ElementsMatch(c.want, got, c.pattern)
+		for _, name := range got {
+			matched, err := fileop.Match(c.pattern, name)
+			assert.NoError(err)
+			assert.True(matched, "%s %s", c.pattern, name)
+		}
+	}
+
+	_, err = fileop.Glob(mfs, "/migu/[")
+	assert.ErrorIs(err, path.ErrBadPattern)
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	for _, c := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.gz", "a.gz", true},
+		{"*.gz", "d/a.gz", false},
+		{"**/*.gz", "a.gz", true},
+		{"**/*.gz", "d/e/a.gz", true},
+		{"d/**", "d/e/a.gz", true},
+		{"d/**/a.gz", "d/a.gz", true},
+		{"d/?/a.gz", "d/ee/a.gz", false},
+	} {
+		matched, err := fileop.Match(c.pattern, c.name)
+		assert.NoError(err)
+		assert.Equal(c.want, matched, "%s %s", c.pattern, c.name)
+	}
+
+	_, err := fileop.Match("[", "a")
+	assert.ErrorIs(err, path.ErrBadPattern)
+}
+
+// prefixFS records the prefixes pushed down to its listings.
+type prefixFS struct {
+	*afero.Handler
+
+	mu       sync.Mutex
+	prefixes []string
+}
+
+func (p *prefixFS) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	p.mu.Lock()
+	p.prefixes = append(p.prefixes, prefix)
+	p.mu.Unlock()
+	infos, err := p.Readdir(dirname, 0)
+	var filtered []fs.FileInfo
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), prefix) {
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered, err
+}
+
+func (p *prefixFS) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	infos, err := p.ReaddirPrefix(dirname, prefix, n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
+
+func TestGlobPrefixMiddleware(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	pfs := &prefixFS{Handler: mfs}
+	writeString(t, mfs, "/logs/2021-12-04.gz", "")
+	writeString(t, mfs, "/logs/2022-01-01.gz", "")
+
+	fsys, err := fileop.WithRetry[fileop.FileSystem](pfs, fileop.RetryPolicy{})
+	assert.NoError(err)
+	got, err := fileop.Glob(fsys, "/logs/2021-*")
+	assert.NoError(err)
+	assert.Equal([]string{"/logs/2021-12-04.gz"}, got)
+	assert.Equal([]string{"2021-"}, pfs.prefixes)
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"path"
 	"path/filepath"
 
+	"github.com/marsgopher/fileop"
 	"github.com/spf13/afero"
 )
 
@@ -73,7 +75,20 @@ func (h *Handler) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
 		return nil, err
 	}
 	defer func() { _ = dir.Close() }()
-	return dir.Readdir(n)
+
+	infos, err := dir.Readdir(n)
+	for i, info := range infos {
+		infos[i] = fileop.WithPath(info, path.Join(dirname, info.Name()))
+	}
+	return infos, err
+}
+
+func (h *Handler) Stat(name string) (fs.FileInfo, error) {
+	info, err := h.Fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return fileop.WithPath(info, name), nil
 }
 
 func (h *Handler) Close() error {
@@ -5,8 +5,10 @@ import (
 	"io"
 	"io/fs"
 	"net"
+	"path"
 
 	hdfs "github.com/colinmarc/hdfs/v2"
+	"github.com/marsgopher/fileop"
 )
 
 const userDefault = "root"
@@ -76,6 +78,39 @@ func (h *Handler) Create(name string) (io.WriteCloser, error) {
 	return fd, nil
 }
 
+// Copy copies src to dst. HDFS has no server side copy, the content is
+// streamed through the client.
+func (h *Handler) Copy(src, dst string) error {
+	rd, err := h.Client.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rd.Close() }()
+
+	wt, err := fileop.NewFileWriter(h, dst, 0, fileop.NONE)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if wt != nil {
+			_ = wt.Close()
+		}
+	}()
+	if _, err := io.Copy(wt, rd); err != nil {
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	defer func() { wt = nil }()
+	return wt.Close()
+}
+
+// Move renames src to dst, creating the parent directory of dst.
+func (h *Handler) Move(src, dst string) error {
+	if err := h.Client.MkdirAll(path.Dir(fileop.CleanPath(dst)), 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	return h.Client.Rename(src, dst)
+}
+
 func (h *Handler) Open(name string) (io.ReadCloser, error) {
 	f, err := h.Client.Open(name)
 	if err != nil {
@@ -100,5 +135,42 @@ func (h *Handler) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
 		return nil, err
 	}
 	defer func() { _ = dir.Close() }()
-	return dir.Readdir(n)
+
+	infos, err := dir.Readdir(n)
+	for i, info := range infos {
+		infos[i] = &fileInfo{FileInfo: info, path: path.Join(fileop.CleanPath(dirname), info.Name())}
+	}
+	return infos, err
+}
+
+func (h *Handler) Stat(name string) (fs.FileInfo, error) {
+	info, err := h.Client.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{FileInfo: info, path: fileop.CleanPath(name)}, nil
+}
+
+// fileInfo adds the full path and fileop.ObjectAttrs to an HDFS FileInfo.
+type fileInfo struct {
+	fs.FileInfo
+	path string
+}
+
+func (f *fileInfo) Path() string { return f.path }
+
+func (f *fileInfo) Sys() interface{} {
+	attrs := &fileop.ObjectAttrs{}
+	if fi, ok := f.FileInfo.(*hdfs.FileInfo); ok {
+		attrs.Owner = fi.Owner()
+		attrs.Group = fi.OwnerGroup()
+	}
+	if status, ok := f.FileInfo.Sys().(interface {
+		GetBlockReplication() uint32
+		GetBlocksize() uint64
+	}); ok {
+		attrs.Replication = int(status.GetBlockReplication())
+		attrs.BlockSize = int64(status.GetBlocksize())
+	}
+	return attrs
 }
@@ -0,0 +1,28 @@
+package minio
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/marsgopher/fileop"
+	minio "github.com/minio/minio-go/v7"
+)
+
+// wrapErr classifies a minio error so that errors.Is works with the fs and
+// fileop error classes.
+func wrapErr(err error) error {
+	var resp minio.ErrorResponse
+	if !errors.As(err, &resp) {
+		return err
+	}
+
+	switch resp.Code {
+	case "NoSuchKey", "NoSuchBucket", "NoSuchUpload", "NoSuchVersion":
+		return fileop.Classify(err, fs.ErrNotExist)
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return fileop.Classify(err, fs.ErrPermission)
+	case "SlowDown", "SlowDownRead", "SlowDownWrite", "RequestLimitExceeded":
+		return fileop.Classify(err, fileop.ErrThrottled)
+	}
+	return fileop.ClassifyHTTPStatus(err, resp.StatusCode)
+}
@@ -2,12 +2,15 @@ package minio
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"mime"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/marsgopher/fileop"
@@ -44,8 +47,8 @@ func (c *Client) Bucket(name string) fileop.FileSystemSimpleBucket {
 func (c *Client) Put(localPath, remotePath string) error {
 	ctx := context.Background()
 	opts := minio.PutObjectOptions{}
-	if _, err := c.Client.FPutObject(ctx, c.bucket, remotePath, localPath, opts); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+	if _, err := c.Client.FPutObject(ctx, c.bucket, fileop.ObjectKey(remotePath), localPath, opts); err != nil {
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 	return nil
 }
@@ -56,25 +59,43 @@ func (c *Client) PutStream(rd io.Reader, remotePath string) error {
 	if opts.ContentType = mime.TypeByExtension(filepath.Ext(remotePath)); opts.ContentType == "" {
 		opts.ContentType = "application/octet-stream"
 	}
-	if _, err := c.Client.PutObject(ctx, c.bucket, remotePath, rd, -1, opts); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+	if _, err := c.Client.PutObject(ctx, c.bucket, fileop.ObjectKey(remotePath), rd, -1, opts); err != nil {
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 	return nil
 }
 
 func (c *Client) PutStreamWithContentType(rd io.Reader, remotePath string, contentType string) error {
+	return c.PutStreamWithOptions(rd, remotePath, fileop.PutOptions{ContentType: contentType})
+}
+
+func (c *Client) PutStreamWithOptions(rd io.Reader, remotePath string, putOpts fileop.PutOptions) error {
 	ctx := context.Background()
-	opts := minio.PutObjectOptions{}
-	if contentType == "" {
+	opts := minio.PutObjectOptions{
+		ContentType:        putOpts.ContentType,
+		ContentEncoding:    putOpts.ContentEncoding,
+		CacheControl:       putOpts.CacheControl,
+		ContentDisposition: putOpts.ContentDisposition,
+		StorageClass:       putOpts.StorageClass,
+		UserMetadata:       putOpts.Metadata,
+	}
+	if opts.ContentType == "" {
 		// try fix content type
-		contentType = mime.TypeByExtension(filepath.Ext(remotePath))
+		opts.ContentType = mime.TypeByExtension(filepath.Ext(remotePath))
 	}
-	if contentType == "" {
-		contentType = "application/octet-stream"
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
 	}
-	opts.ContentType = contentType
-	if _, err := c.Client.PutObject(ctx, c.bucket, remotePath, rd, -1, opts); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+	if putOpts.ACL != "" {
+		opts.UserMetadata = make(map[string]string, len(putOpts.Metadata)+1)
+		for k, v := range putOpts.Metadata {
+			opts.UserMetadata[k] = v
+		}
+		// sent as header rather than x-amz-meta-*
+		opts.UserMetadata["x-amz-acl"] = putOpts.ACL
+	}
+	if _, err := c.Client.PutObject(ctx, c.bucket, fileop.ObjectKey(remotePath), rd, -1, opts); err != nil {
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 	return nil
 }
@@ -82,17 +103,102 @@ func (c *Client) PutStreamWithContentType(rd io.Reader, remotePath string, conte
 func (c *Client) PutEmpty(remotePath string) error {
 	ctx := context.Background()
 	opts := minio.PutObjectOptions{}
-	if _, err := c.Client.PutObject(ctx, c.bucket, remotePath, nil, 0, opts); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+	if _, err := c.Client.PutObject(ctx, c.bucket, fileop.ObjectKey(remotePath), nil, 0, opts); err != nil {
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 	return nil
 }
 
 func (c *Client) Exist(remotePath string) bool {
+	ok, _ := c.ExistE(remotePath)
+	return ok
+}
+
+func (c *Client) ExistE(remotePath string) (bool, error) {
 	ctx := context.Background()
 	opts := minio.StatObjectOptions{}
-	_, err := c.Client.StatObject(ctx, c.bucket, remotePath, opts)
-	return err == nil
+	if _, err := c.Client.StatObject(ctx, c.bucket, fileop.ObjectKey(remotePath), opts); err != nil {
+		if err = wrapErr(err); errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("exist %s: %w", remotePath, err)
+	}
+	return true, nil
+}
+
+func (c *Client) Remove(remotePath string) error {
+	ctx := context.Background()
+	if err := c.Client.RemoveObject(ctx, c.bucket, fileop.ObjectKey(remotePath), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("remove %s: %w", remotePath, wrapErr(err))
+	}
+	return nil
+}
+
+// RemoveBatch removes the objects with multi-object delete requests.
+func (c *Client) RemoveBatch(remotePaths []string) []error {
+	index := make(map[string][]int, len(remotePaths))
+	objCh := make(chan minio.ObjectInfo, len(remotePaths))
+	for i, remotePath := range remotePaths {
+		key := fileop.ObjectKey(remotePath)
+		index[key] = append(index[key], i)
+		objCh <- minio.ObjectInfo{Key: key}
+	}
+	close(objCh)
+
+	var errs []error
+	for rmErr := range c.Client.RemoveObjects(context.Background(), c.bucket, objCh, minio.RemoveObjectsOptions{}) {
+		if errs == nil {
+			errs = make([]error, len(remotePaths))
+		}
+		failed, ok := index[rmErr.ObjectName]
+		if !ok {
+			// request level failure, e.g. an invalid bucket name
+			for i := range remotePaths {
+				errs[i] = fmt.Errorf("remove %s: %w", remotePaths[i], wrapErr(rmErr.Err))
+			}
+			continue
+		}
+		for _, i := range failed {
+			errs[i] = fmt.Errorf("remove %s: %w", remotePaths[i], wrapErr(rmErr.Err))
+		}
+	}
+	return errs
+}
+
+// Copy copies the object src to dst server side.
+func (c *Client) Copy(src, dst string) error {
+	ctx := context.Background()
+	dstOpts := minio.CopyDestOptions{Bucket: c.bucket, Object: fileop.ObjectKey(dst)}
+	srcOpts := minio.CopySrcOptions{Bucket: c.bucket, Object: fileop.ObjectKey(src)}
+	// ComposeObject falls back to a multipart copy above the 5GiB limit of CopyObject
+	if _, err := c.Client.ComposeObject(ctx, dstOpts, srcOpts); err != nil {
+		return fmt.Errorf("copy %s: %w", src, wrapErr(err))
+	}
+	return nil
+}
+
+// Move copies the object src to dst server side and removes src.
+func (c *Client) Move(src, dst string) error {
+	if err := c.Copy(src, dst); err != nil {
+		return err
+	}
+	return c.Remove(src)
+}
+
+func (c *Client) PresignGet(name string, ttl time.Duration) (string, error) {
+	u, err := c.Client.PresignedGetObject(context.Background(), c.bucket, fileop.ObjectKey(name), ttl, nil)
+	if err != nil {
+		return "", fmt.Errorf("presign get %s: %w", name, wrapErr(err))
+	}
+	return u.String(), nil
+}
+
+func (c *Client) PresignPut(name string, ttl time.Duration) (string, error) {
+	u, err := c.Client.PresignedPutObject(context.Background(), c.bucket, fileop.ObjectKey(name), ttl)
+	if err != nil {
+		return "", fmt.Errorf("presign put %s: %w", name, wrapErr(err))
+	}
+	return u.String(), nil
 }
 
 func (c *Client) Close() error {
@@ -100,63 +206,163 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) Open(name string) (io.ReadCloser, error) {
-	object, err := c.Client.GetObject(context.Background(), c.bucket, name, minio.GetObjectOptions{})
+	object, err := c.Client.GetObject(context.Background(), c.bucket, fileop.ObjectKey(name), minio.GetObjectOptions{})
 	if err != nil {
-		return nil, err
+		return nil, wrapErr(err)
 	}
 
-	return object, nil
+	return &objectReader{Object: object}, nil
 }
 
 func (c *Client) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	return c.readdir(dirname, "", n)
+}
+
+func (c *Client) Readdirnames(dirname string, n int) ([]string, error) {
+	infos, err := c.readdir(dirname, "", n)
+	if err != nil {
+		return nil, err
+	}
+	return infoNames(infos), nil
+}
+
+// ReaddirPrefix lists the entries of dirname whose names start with prefix.
+func (c *Client) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	return c.readdir(dirname, prefix, n)
+}
+
+// ReaddirnamesPrefix lists the names in dirname that start with prefix.
+func (c *Client) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	infos, err := c.readdir(dirname, prefix, n)
+	if err != nil {
+		return nil, err
+	}
+	return infoNames(infos), nil
+}
+
+// readdir lists at most n (all if n <= 0) entries of dirname whose names
+// start with prefix.
+func (c *Client) readdir(dirname, prefix string, n int) ([]fs.FileInfo, error) {
 	if n < 0 {
 		n = 0
 	}
 
-	objectsCh := c.Client.ListObjects(context.Background(), c.bucket, minio.ListObjectsOptions{
-		Prefix:    dirname,
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := fileop.CleanPath(dirname)
+	dirKey := fileop.DirKey(dirname)
+	objectsCh := c.Client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{
+		Prefix:    dirKey + prefix,
 		Recursive: false,
 		MaxKeys:   n,
 	})
 
 	fileInfos := make([]fs.FileInfo, 0, n)
 	for obj := range objectsCh {
+		if obj.Err != nil {
+			return nil, wrapErr(obj.Err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(obj.Key, dirKey), "/")
+		if name == "" {
+			// directory marker object
+			continue
+		}
 		fileInfo := &minioFileInfo{
-			name:    obj.Key,
+			name:    name,
+			path:    path.Join(dir, name),
 			size:    obj.Size,
 			modTime: obj.LastModified,
-			isDir:   obj.Key[len(obj.Key)-1] == '/',
+			isDir:   strings.HasSuffix(obj.Key, "/"),
+			attrs:   objectAttrs(obj),
 		}
 		fileInfos = append(fileInfos, fileInfo)
+		if n > 0 && len(fileInfos) == n {
+			break
+		}
 	}
 
 	return fileInfos, nil
 }
 
-func (c *Client) Readdirnames(dirname string, n int) ([]string, error) {
-	if n < 0 {
-		n = 0
+// Stat returns the FileInfo of the named object. A name without object
+// that prefixes other objects is reported as a directory.
+func (c *Client) Stat(name string) (fs.FileInfo, error) {
+	p := fileop.CleanPath(name)
+	key := fileop.ObjectKey(name)
+
+	var statErr error
+	if key != "" {
+		obj, err := c.Client.StatObject(context.Background(), c.bucket, key, minio.StatObjectOptions{})
+		if err == nil {
+			fileInfo := &minioFileInfo{
+				name:    path.Base(p),
+				path:    p,
+				size:    obj.Size,
+				modTime: obj.LastModified,
+				attrs:   objectAttrs(obj),
+			}
+			return fileInfo, nil
+		}
+		if statErr = wrapErr(err); !errors.Is(statErr, fs.ErrNotExist) {
+			return nil, fmt.Errorf("stat %s: %w", name, statErr)
+		}
 	}
 
-	objectsCh := c.Client.ListObjects(context.Background(), c.bucket, minio.ListObjectsOptions{
-		Prefix:    dirname,
-		Recursive: false,
-		MaxKeys:   n,
-	})
+	infos, err := c.readdir(name, "", 1)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", name, err)
+	}
+	if key != "" && len(infos) == 0 {
+		return nil, fmt.Errorf("stat %s: %w", name, statErr)
+	}
+	fileInfo := &minioFileInfo{
+		name:  path.Base(p),
+		path:  p,
+		isDir: true,
+		attrs: &fileop.ObjectAttrs{},
+	}
+	return fileInfo, nil
+}
 
-	names := make([]string, 0, n)
-	for obj := range objectsCh {
-		names = append(names, obj.Key)
+func objectAttrs(obj minio.ObjectInfo) *fileop.ObjectAttrs {
+	return &fileop.ObjectAttrs{
+		ETag:         obj.ETag,
+		ContentType:  obj.ContentType,
+		StorageClass: obj.StorageClass,
+		Metadata:     obj.UserMetadata,
+	}
+}
+
+func infoNames(infos []fs.FileInfo) []string {
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
 	}
+	return names
+}
 
-	return names, nil
+// objectReader reports read errors of a lazily fetched object with the
+// fileop error classes.
+type objectReader struct {
+	*minio.Object
+}
+
+func (o *objectReader) Read(p []byte) (int, error) {
+	n, err := o.Object.Read(p)
+	if err != nil && err != io.EOF {
+		err = wrapErr(err)
+	}
+	return n, err
 }
 
 type minioFileInfo struct {
 	name    string
+	path    string
 	size    int64
 	modTime time.Time
 	isDir   bool
+	attrs   *fileop.ObjectAttrs
 }
 
 func (f *minioFileInfo) Mode() fs.FileMode {
@@ -167,7 +373,8 @@ func (f *minioFileInfo) Mode() fs.FileMode {
 }
 
 func (f *minioFileInfo) Name() string       { return f.name }
+func (f *minioFileInfo) Path() string       { return f.path }
 func (f *minioFileInfo) Size() int64        { return f.size }
 func (f *minioFileInfo) ModTime() time.Time { return f.modTime }
 func (f *minioFileInfo) IsDir() bool        { return f.isDir }
-func (f *minioFileInfo) Sys() interface{}   { return nil }
+func (f *minioFileInfo) Sys() interface{}   { return f.attrs }
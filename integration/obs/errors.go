@@ -0,0 +1,28 @@
+package obs
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
+	"github.com/marsgopher/fileop"
+)
+
+// wrapErr classifies an obs error so that errors.Is works with the fs and
+// fileop error classes.
+func wrapErr(err error) error {
+	var obsErr obs.ObsError
+	if !errors.As(err, &obsErr) {
+		return err
+	}
+
+	switch obsErr.Code {
+	case "NoSuchKey", "NoSuchBucket", "NoSuchUpload":
+		return fileop.Classify(err, fs.ErrNotExist)
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return fileop.Classify(err, fs.ErrPermission)
+	case "SlowDown", "TooManyRequests":
+		return fileop.Classify(err, fileop.ErrThrottled)
+	}
+	return fileop.ClassifyHTTPStatus(err, obsErr.StatusCode)
+}
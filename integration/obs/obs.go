@@ -7,7 +7,10 @@ import (
 	"io/fs"
 	"mime"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
@@ -16,6 +19,12 @@ import (
 
 var ErrNotSetup = errors.New("not setup")
 
+// maxListKeys is the page size limit of a single ListObjects request.
+const maxListKeys = 1000
+
+// maxDeleteKeys is the key limit of a single DeleteObjects request.
+const maxDeleteKeys = 1000
+
 type GetAclInputFunc func(key string) *obs.SetObjectAclInput
 
 type Client struct {
@@ -75,16 +84,16 @@ func (c *Client) Bucket(name string) fileop.FileSystemSimpleBucket {
 func (c *Client) Put(localPath, remotePath string) error {
 	input := &obs.PutFileInput{}
 	input.Bucket = c.bucket
-	input.Key = remotePath
+	input.Key = fileop.ObjectKey(remotePath)
 	input.SourceFile = localPath
 
 	if _, err := c.ObsClient.PutFile(input); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 
-	if aclInput := c.getAclInput(remotePath); aclInput != nil {
+	if aclInput := c.getAclInput(fileop.ObjectKey(remotePath)); aclInput != nil {
 		if _, err := c.ObsClient.SetObjectAcl(aclInput); err != nil {
-			return fmt.Errorf("set acl %s: %w", remotePath, err)
+			return fmt.Errorf("set acl %s: %w", remotePath, wrapErr(err))
 		}
 	}
 	return nil
@@ -93,22 +102,29 @@ func (c *Client) Put(localPath, remotePath string) error {
 func (c *Client) PutStream(reader io.Reader, remotePath string) error {
 	input := &obs.PutObjectInput{}
 	input.Bucket = c.bucket
-	input.Key = remotePath
+	input.Key = fileop.ObjectKey(remotePath)
 	input.Body = reader
 
 	if _, err := c.ObsClient.PutObject(input); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 
-	if aclInput := c.getAclInput(remotePath); aclInput != nil {
+	if aclInput := c.getAclInput(fileop.ObjectKey(remotePath)); aclInput != nil {
 		if _, err := c.ObsClient.SetObjectAcl(aclInput); err != nil {
-			return fmt.Errorf("set acl %s: %w", remotePath, err)
+			return fmt.Errorf("set acl %s: %w", remotePath, wrapErr(err))
 		}
 	}
 	return nil
 }
 
 func (c *Client) PutStreamWithContentType(reader io.Reader, remotePath string, contentType string) error {
+	return c.PutStreamWithOptions(reader, remotePath, fileop.PutOptions{ContentType: contentType})
+}
+
+// PutStreamWithOptions uploads reader with the given object properties.
+// A canned ACL in opts replaces the ACL configured for the client.
+func (c *Client) PutStreamWithOptions(reader io.Reader, remotePath string, opts fileop.PutOptions) error {
+	contentType := opts.ContentType
 	if contentType == "" {
 		// try fix content type
 		contentType = mime.TypeByExtension(filepath.Ext(remotePath))
@@ -119,17 +135,26 @@ func (c *Client) PutStreamWithContentType(reader io.Reader, remotePath string, c
 
 	input := &obs.PutObjectInput{}
 	input.Bucket = c.bucket
-	input.Key = remotePath
+	input.Key = fileop.ObjectKey(remotePath)
 	input.Body = reader
 	input.ContentType = contentType
+	input.ContentEncoding = opts.ContentEncoding
+	input.CacheControl = opts.CacheControl
+	input.ContentDisposition = opts.ContentDisposition
+	input.Metadata = opts.Metadata
+	input.StorageClass = obs.StorageClassType(opts.StorageClass)
+	input.ACL = obs.AclType(opts.ACL)
 
 	if _, err := c.ObsClient.PutObject(input); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 
-	if aclInput := c.getAclInput(remotePath); aclInput != nil {
+	if opts.ACL != "" {
+		return nil
+	}
+	if aclInput := c.getAclInput(fileop.ObjectKey(remotePath)); aclInput != nil {
 		if _, err := c.ObsClient.SetObjectAcl(aclInput); err != nil {
-			return fmt.Errorf("set acl %s: %w", remotePath, err)
+			return fmt.Errorf("set acl %s: %w", remotePath, wrapErr(err))
 		}
 	}
 	return nil
@@ -138,18 +163,18 @@ func (c *Client) PutStreamWithContentType(reader io.Reader, remotePath string, c
 func (c *Client) PutEmpty(remotePath string) error {
 	input := &obs.PutFileInput{}
 	input.Bucket = c.bucket
-	input.Key = remotePath
-	aclInput := c.getAclInput(remotePath)
+	input.Key = fileop.ObjectKey(remotePath)
+	aclInput := c.getAclInput(fileop.ObjectKey(remotePath))
 
 	if _, err := c.ObsClient.PutObject(&obs.PutObjectInput{
 		PutObjectBasicInput: input.PutObjectBasicInput,
 	}); err != nil {
-		return fmt.Errorf("put %s: %w", remotePath, err)
+		return fmt.Errorf("put %s: %w", remotePath, wrapErr(err))
 	}
 
 	if aclInput != nil {
 		if _, err := c.ObsClient.SetObjectAcl(aclInput); err != nil {
-			return fmt.Errorf("set acl %s: %w", remotePath, err)
+			return fmt.Errorf("set acl %s: %w", remotePath, wrapErr(err))
 		}
 	}
 	return nil
@@ -163,95 +188,302 @@ func (c *Client) PutFinish(remotePath string) error {
 }
 
 func (c *Client) Exist(path string) bool {
+	ok, _ := c.ExistE(path)
+	return ok
+}
+
+func (c *Client) ExistE(path string) (bool, error) {
 	input := &obs.GetObjectMetadataInput{}
 	input.Bucket = c.bucket
-	input.Key = path
-	_, err := c.GetObjectMetadata(input)
-	return err == nil
+	input.Key = fileop.ObjectKey(path)
+	if _, err := c.GetObjectMetadata(input); err != nil {
+		if err = wrapErr(err); errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("exist %s: %w", path, err)
+	}
+	return true, nil
 }
 
-func (c *Client) Close() error {
-	c.ObsClient.Close()
+func (c *Client) Remove(remotePath string) error {
+	input := &obs.DeleteObjectInput{}
+	input.Bucket = c.bucket
+	input.Key = fileop.ObjectKey(remotePath)
+	if _, err := c.ObsClient.DeleteObject(input); err != nil {
+		return fmt.Errorf("remove %s: %w", remotePath, wrapErr(err))
+	}
 	return nil
 }
 
-func (c *Client) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
-	input := &obs.ListObjectsInput{}
+// RemoveBatch removes the objects with DeleteObjects requests of up to
+// maxDeleteKeys keys each.
+func (c *Client) RemoveBatch(remotePaths []string) []error {
+	var errs []error
+	fail := func(i int, err error) {
+		if errs == nil {
+			errs = make([]error, len(remotePaths))
+		}
+		errs[i] = fmt.Errorf("remove %s: %w", remotePaths[i], wrapErr(err))
+	}
+
+	for start := 0; start < len(remotePaths); start += maxDeleteKeys {
+		end := min(start+maxDeleteKeys, len(remotePaths))
+		index := make(map[string][]int, end-start)
+		input := &obs.DeleteObjectsInput{}
+		input.Bucket = c.bucket
+		input.Quiet = true
+		for i := start; i < end; i++ {
+			key := fileop.ObjectKey(remotePaths[i])
+			if _, ok := index[key]; !ok {
+				input.Objects = append(input.Objects, obs.ObjectToDelete{Key: key})
+			}
+			index[key] = append(index[key], i)
+		}
+
+		output, err := c.ObsClient.DeleteObjects(input)
+		if err != nil {
+			for i := start; i < end; i++ {
+				fail(i, err)
+			}
+			continue
+		}
+		for _, e := range output.Errors {
+			err := obs.ObsError{Code: e.Code, Message: e.Message, Resource: e.Key}
+			for _, i := range index[e.Key] {
+				fail(i, err)
+			}
+		}
+	}
+	return errs
+}
+
+// Copy copies the object src to dst server side.
+func (c *Client) Copy(src, dst string) error {
+	input := &obs.CopyObjectInput{}
 	input.Bucket = c.bucket
-	input.Prefix = dirname
-	input.MaxKeys = n
-	input.Delimiter = "/"
+	input.Key = fileop.ObjectKey(dst)
+	input.CopySourceBucket = c.bucket
+	input.CopySourceKey = fileop.ObjectKey(src)
 
-	output, err := c.ListObjects(input)
-	if err != nil {
-		return nil, err
+	if _, err := c.ObsClient.CopyObject(input); err != nil {
+		return fmt.Errorf("copy %s: %w", src, wrapErr(err))
 	}
 
-	fileInfos := make([]fs.FileInfo, 0, len(output.Contents)+len(output.CommonPrefixes))
-	for _, object := range output.Contents {
-		fileInfo := &obsFileInfo{
-			name:    object.Key,
-			size:    object.Size,
-			modTime: object.LastModified,
-			isDir:   false,
+	if aclInput := c.getAclInput(fileop.ObjectKey(dst)); aclInput != nil {
+		if _, err := c.ObsClient.SetObjectAcl(aclInput); err != nil {
+			return fmt.Errorf("set acl %s: %w", dst, wrapErr(err))
 		}
-		fileInfos = append(fileInfos, fileInfo)
 	}
+	return nil
+}
 
-	for _, prefix := range output.CommonPrefixes {
-		fileInfo := &obsFileInfo{
-			name:    prefix,
-			isDir:   true,
-			modTime: time.Now(),
-		}
-		fileInfos = append(fileInfos, fileInfo)
+// Move copies the object src to dst server side and removes src.
+func (c *Client) Move(src, dst string) error {
+	if err := c.Copy(src, dst); err != nil {
+		return err
 	}
+	return c.Remove(src)
+}
 
-	return fileInfos, nil
+func (c *Client) PresignGet(name string, ttl time.Duration) (string, error) {
+	return c.presign(obs.HttpMethodGet, name, ttl)
+}
+
+func (c *Client) PresignPut(name string, ttl time.Duration) (string, error) {
+	return c.presign(obs.HttpMethodPut, name, ttl)
+}
+
+func (c *Client) presign(method obs.HttpMethodType, name string, ttl time.Duration) (string, error) {
+	input := &obs.CreateSignedUrlInput{}
+	input.Method = method
+	input.Bucket = c.bucket
+	input.Key = fileop.ObjectKey(name)
+	input.Expires = int(ttl / time.Second)
+
+	output, err := c.ObsClient.CreateSignedUrl(input)
+	if err != nil {
+		return "", fmt.Errorf("presign %s %s: %w", method, name, wrapErr(err))
+	}
+	return output.SignedUrl, nil
+}
+
+func (c *Client) Close() error {
+	c.ObsClient.Close()
+	return nil
+}
+
+func (c *Client) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	return c.readdir(dirname, "", n)
 }
 
 func (c *Client) Readdirnames(dirname string, n int) ([]string, error) {
+	infos, err := c.readdir(dirname, "", n)
+	if err != nil {
+		return nil, err
+	}
+	return infoNames(infos), nil
+}
+
+// ReaddirPrefix lists the entries of dirname whose names start with prefix.
+func (c *Client) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	return c.readdir(dirname, prefix, n)
+}
+
+// ReaddirnamesPrefix lists the names in dirname that start with prefix.
+func (c *Client) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	infos, err := c.readdir(dirname, prefix, n)
+	if err != nil {
+		return nil, err
+	}
+	return infoNames(infos), nil
+}
+
+// readdir lists at most n (all if n <= 0) entries of dirname whose names
+// start with prefix, following the listing markers across pages.
+func (c *Client) readdir(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	dir := fileop.CleanPath(dirname)
+	dirKey := fileop.DirKey(dirname)
+
 	input := &obs.ListObjectsInput{}
 	input.Bucket = c.bucket
-	input.Prefix = dirname
-	input.MaxKeys = n
+	input.Prefix = dirKey + prefix
 	input.Delimiter = "/"
+	if n > 0 && n < maxListKeys {
+		input.MaxKeys = n
+	}
 
-	output, err := c.ListObjects(input)
-	if err != nil {
-		return nil, err
+	var fileInfos []fs.FileInfo
+	for {
+		output, err := c.ListObjects(input)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+
+		for _, object := range output.Contents {
+			name := strings.TrimPrefix(object.Key, dirKey)
+			if name == "" || strings.HasSuffix(name, "/") {
+				// directory marker object
+				continue
+			}
+			fileInfo := &obsFileInfo{
+				name:    name,
+				path:    path.Join(dir, name),
+				size:    object.Size,
+				modTime: object.LastModified,
+				isDir:   false,
+				attrs: &fileop.ObjectAttrs{
+					ETag:         object.ETag,
+					StorageClass: string(object.StorageClass),
+				},
+			}
+			fileInfos = append(fileInfos, fileInfo)
+		}
+
+		for _, prefix := range output.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(prefix, dirKey), "/")
+			fileInfo := &obsFileInfo{
+				name:  name,
+				path:  path.Join(dir, name),
+				isDir: true,
+				attrs: &fileop.ObjectAttrs{},
+			}
+			fileInfos = append(fileInfos, fileInfo)
+		}
+
+		if !output.IsTruncated || (n > 0 && len(fileInfos) >= n) {
+			break
+		}
+		input.Marker = output.NextMarker
 	}
 
-	names := make([]string, 0, len(output.Contents)+len(output.CommonPrefixes))
-	for _, object := range output.Contents {
-		names = append(names, object.Key)
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return fileInfos[i].Name() < fileInfos[j].Name()
+	})
+	if n > 0 && len(fileInfos) > n {
+		fileInfos = fileInfos[:n]
 	}
+	return fileInfos, nil
+}
 
-	names = append(names, output.CommonPrefixes...)
+// Stat returns the FileInfo of the named object. A name without object
+// that prefixes other objects is reported as a directory.
+func (c *Client) Stat(name string) (fs.FileInfo, error) {
+	p := fileop.CleanPath(name)
+	key := fileop.ObjectKey(name)
+
+	var statErr error
+	if key != "" {
+		input := &obs.GetObjectMetadataInput{}
+		input.Bucket = c.bucket
+		input.Key = key
+		output, err := c.GetObjectMetadata(input)
+		if err == nil {
+			fileInfo := &obsFileInfo{
+				name:    path.Base(p),
+				path:    p,
+				size:    output.ContentLength,
+				modTime: output.LastModified,
+				attrs: &fileop.ObjectAttrs{
+					ETag:         output.ETag,
+					ContentType:  output.ContentType,
+					StorageClass: string(output.StorageClass),
+					Metadata:     output.Metadata,
+				},
+			}
+			return fileInfo, nil
+		}
+		if statErr = wrapErr(err); !errors.Is(statErr, fs.ErrNotExist) {
+			return nil, fmt.Errorf("stat %s: %w", name, statErr)
+		}
+	}
 
-	return names, nil
+	infos, err := c.readdir(name, "", 1)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", name, err)
+	}
+	if key != "" && len(infos) == 0 {
+		return nil, fmt.Errorf("stat %s: %w", name, statErr)
+	}
+	fileInfo := &obsFileInfo{
+		name:  path.Base(p),
+		path:  p,
+		isDir: true,
+		attrs: &fileop.ObjectAttrs{},
+	}
+	return fileInfo, nil
+}
+
+func infoNames(infos []fs.FileInfo) []string {
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
 }
 
 func (c *Client) Open(name string) (io.ReadCloser, error) {
 	input := &obs.GetObjectInput{}
 	input.Bucket = c.bucket
-	input.Key = name
+	input.Key = fileop.ObjectKey(name)
 
 	output, err := c.GetObject(input)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr(err)
 	}
 	return output.Body, nil
 }
 
 type obsFileInfo struct {
 	name    string
+	path    string
 	size    int64
 	modTime time.Time
 	isDir   bool
+	attrs   *fileop.ObjectAttrs
 }
 
 func (f *obsFileInfo) Name() string       { return f.name }
+func (f *obsFileInfo) Path() string       { return f.path }
 func (f *obsFileInfo) Size() int64        { return f.size }
 func (f *obsFileInfo) ModTime() time.Time { return f.modTime }
 func (f *obsFileInfo) IsDir() bool        { return f.isDir }
@@ -261,4 +493,4 @@ func (f *obsFileInfo) Mode() fs.FileMode {
 	}
 	return 0644
 }
-func (f *obsFileInfo) Sys() interface{} { return nil }
+func (f *obsFileInfo) Sys() interface{} { return f.attrs }
@@ -6,4 +6,8 @@ type Config struct {
 	Password  string            `mapstructure:"password"`
 	Hosts     map[string]string `mapstructure:"hosts"`
 	UserAgent string            `mapstructure:"user_agent"`
+
+	// for token anti-leech download URLs, see PresignGet
+	Domain      string `mapstructure:"domain"`
+	TokenSecret string `mapstructure:"token_secret"`
 }
@@ -0,0 +1,18 @@
+package upyun
+
+import (
+	"errors"
+
+	"github.com/marsgopher/fileop"
+	"github.com/upyun/go-sdk/v3/upyun"
+)
+
+// wrapErr classifies an upyun error so that errors.Is works with the fs and
+// fileop error classes.
+func wrapErr(err error) error {
+	var upErr *upyun.Error
+	if !errors.As(err, &upErr) {
+		return err
+	}
+	return fileop.ClassifyHTTPStatus(err, upErr.StatusCode)
+}
@@ -2,26 +2,36 @@ package upyun
 
 import (
 	"io/fs"
+	"strings"
 	"time"
 
+	"github.com/marsgopher/fileop"
 	"github.com/upyun/go-sdk/v3/upyun"
 )
 
+// metaPrefix is the header prefix of user metadata.
+const metaPrefix = "x-upyun-meta-"
+
 type fileInfo struct {
 	*upyun.FileInfo
+	path string
 }
 
 func (f fileInfo) Name() string {
 	return f.FileInfo.Name
 }
 
+func (f fileInfo) Path() string {
+	return f.path
+}
+
 func (f fileInfo) Size() int64 {
 	return f.FileInfo.Size
 }
 
 func (f fileInfo) Mode() fs.FileMode {
 	if f.IsDir() {
-		return 0777
+		return fs.ModeDir | 0777
 	}
 	return 0666
 }
@@ -35,5 +45,15 @@ func (f fileInfo) IsDir() bool {
 }
 
 func (f fileInfo) Sys() interface{} {
-	return nil
+	attrs := &fileop.ObjectAttrs{
+		ETag:        f.FileInfo.MD5,
+		ContentType: f.FileInfo.ContentType,
+	}
+	if len(f.FileInfo.Meta) > 0 {
+		attrs.Metadata = make(map[string]string, len(f.FileInfo.Meta))
+		for k, v := range f.FileInfo.Meta {
+			attrs.Metadata[strings.TrimPrefix(k, metaPrefix)] = v
+		}
+	}
+	return attrs
 }
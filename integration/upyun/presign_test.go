@@ -0,0 +1,29 @@
+package upyun
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+)
+
+func TestPresignGet(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	// md5("secret&1700000000&/dict/a%20b.txt") = 556b842d806df4ade7c08a3912edfb34
+	w := &Client{domain: "cdn.example.com", tokenSecret: "secret"}
+	u, err := w.presignGet("dict/a b.txt", time.Unix(1700000000, 0))
+	assert.NoError(err)
+	assert.Equal("https://cdn.example.com/dict/a%20b.txt?_upt=f4ade7c01700000000", u)
+
+	w.domain = "http://cdn.example.com"
+	u, err = w.presignGet("/dict/a b.txt", time.Unix(1700000000, 0))
+	assert.NoError(err)
+	assert.Equal("http://cdn.example.com/dict/a%20b.txt?_upt=f4ade7c01700000000", u)
+
+	_, err = (&Client{domain: "cdn.example.com"}).PresignGet("/a", time.Minute)
+	assert.ErrorIs(err, fileop.ErrUnsupported)
+}
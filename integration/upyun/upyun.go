@@ -1,19 +1,23 @@
 package upyun
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"mime"
-	"net/http"
-	"os"
+	"path"
 	"path/filepath"
 
 	"github.com/marsgopher/common/concurrency"
+	"github.com/marsgopher/fileop"
 	"github.com/upyun/go-sdk/v3/upyun"
 )
 
 type Client struct {
 	*upyun.UpYun
+	domain      string
+	tokenSecret string
 }
 
 func New(c Config) (*Client, error) {
@@ -24,28 +28,39 @@ func New(c Config) (*Client, error) {
 		Hosts:     c.Hosts,
 		UserAgent: c.UserAgent,
 	})
-	return &Client{UpYun: upFS}, nil
+	return &Client{
+		UpYun:       upFS,
+		domain:      c.Domain,
+		tokenSecret: c.TokenSecret,
+	}, nil
 }
 
 func (w *Client) Put(localPath, remotePath string) error {
-	return w.UpYun.Put(&upyun.PutObjectConfig{
+	return wrapErr(w.UpYun.Put(&upyun.PutObjectConfig{
 		LocalPath: localPath,
-		Path:      remotePath,
+		Path:      upyunPath(remotePath),
 		Headers:   map[string]string{"Content-Type": "application/octet-stream"},
 		UseMD5:    true,
-	})
+	}))
 }
 
 func (w *Client) PutStream(reader io.Reader, remotePath string) error {
-	return w.UpYun.Put(&upyun.PutObjectConfig{
+	return wrapErr(w.UpYun.Put(&upyun.PutObjectConfig{
 		Reader:  reader,
-		Path:    remotePath,
+		Path:    upyunPath(remotePath),
 		Headers: map[string]string{"Content-Type": "application/octet-stream"},
 		UseMD5:  true,
-	})
+	}))
 }
 
 func (w *Client) PutStreamWithContentType(reader io.Reader, remotePath string, contentType string) error {
+	return w.PutStreamWithOptions(reader, remotePath, fileop.PutOptions{ContentType: contentType})
+}
+
+// PutStreamWithOptions uploads reader with the given object properties.
+// upyun has no storage classes or canned ACLs, those options are ignored.
+func (w *Client) PutStreamWithOptions(reader io.Reader, remotePath string, opts fileop.PutOptions) error {
+	contentType := opts.ContentType
 	if contentType == "" {
 		// try fix content type
 		contentType = mime.TypeByExtension(filepath.Ext(remotePath))
@@ -53,40 +68,64 @@ func (w *Client) PutStreamWithContentType(reader io.Reader, remotePath string, c
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
-	return w.UpYun.Put(&upyun.PutObjectConfig{
+
+	headers := map[string]string{"Content-Type": contentType}
+	if v := opts.ContentEncoding; v != "" {
+		headers["Content-Encoding"] = v
+	}
+	if v := opts.CacheControl; v != "" {
+		headers["Cache-Control"] = v
+	}
+	if v := opts.ContentDisposition; v != "" {
+		headers["Content-Disposition"] = v
+	}
+	for k, v := range opts.Metadata {
+		headers[metaPrefix+k] = v
+	}
+	return wrapErr(w.UpYun.Put(&upyun.PutObjectConfig{
 		Reader:  reader,
-		Path:    remotePath,
-		Headers: map[string]string{"Content-Type": contentType},
+		Path:    upyunPath(remotePath),
+		Headers: headers,
 		UseMD5:  true,
-	})
+	}))
 }
 
 func (w *Client) PutEmpty(remote string) error {
-	return w.UpYun.Put(&upyun.PutObjectConfig{
-		Path: remote,
-	})
+	return wrapErr(w.UpYun.Put(&upyun.PutObjectConfig{
+		Path: upyunPath(remote),
+	}))
 }
 
 func (w *Client) PutFinish(remote string) error {
 	target := remote + ".finish"
-	return w.UpYun.Put(&upyun.PutObjectConfig{
-		Path: target,
-	})
+	return wrapErr(w.UpYun.Put(&upyun.PutObjectConfig{
+		Path: upyunPath(target),
+	}))
 }
 
 func (w *Client) Exist(remote string) bool {
-	_, err := w.UpYun.GetInfo(remote)
-	return err == nil
+	ok, _ := w.ExistE(remote)
+	return ok
+}
+
+func (w *Client) ExistE(remote string) (bool, error) {
+	if _, err := w.UpYun.GetInfo(upyunPath(remote)); err != nil {
+		if err = wrapErr(err); errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("exist %s: %w", remote, err)
+	}
+	return true, nil
 }
 
 func (w *Client) Open(name string) (io.ReadCloser, error) {
 	rd, wt := io.Pipe()
 	go func() {
 		_, err := w.Get(&upyun.GetObjectConfig{
-			Path:   name,
+			Path:   upyunPath(name),
 			Writer: wt,
 		})
-		_ = wt.CloseWithError(err)
+		_ = wt.CloseWithError(wrapErr(err))
 	}()
 	return rd, nil
 }
@@ -97,17 +136,11 @@ func (w *Client) Readdirnames(name string, n int) ([]string, error) {
 	wg := concurrency.NewSemaErrGroup(1)
 	wg.Do(func() error {
 		if err := w.List(&upyun.GetObjectsConfig{
-			Path:           name,
+			Path:           upyunPath(name),
 			ObjectsChan:    objCh,
 			MaxListObjects: n,
 		}); err != nil {
-			if e, ok := err.(*upyun.Error); ok {
-				if e.StatusCode == http.StatusNotFound {
-					return os.ErrNotExist
-				}
-			}
-
-			return err
+			return wrapErr(err)
 		}
 		return nil
 	})
@@ -119,32 +152,80 @@ func (w *Client) Readdirnames(name string, n int) ([]string, error) {
 }
 
 func (w *Client) Readdir(name string, n int) ([]fs.FileInfo, error) {
+	dir := fileop.CleanPath(name)
 	objCh := make(chan *upyun.FileInfo)
 	var res []fs.FileInfo
 	wg := concurrency.NewSemaErrGroup(1)
 	wg.Do(func() error {
 		if err := w.List(&upyun.GetObjectsConfig{
-			Path:           name,
+			Path:           upyunPath(name),
 			ObjectsChan:    objCh,
 			MaxListObjects: n,
 		}); err != nil {
-			if e, ok := err.(*upyun.Error); ok {
-				if e.StatusCode == http.StatusNotFound {
-					return os.ErrNotExist
-				}
-			}
-
-			return err
+			return wrapErr(err)
 		}
 		return nil
 	})
 	for obj := range objCh {
-		res = append(res, fileInfo{obj})
+		res = append(res, fileInfo{FileInfo: obj, path: path.Join(dir, obj.Name)})
 	}
 
 	return res, wg.Wait()
 }
 
+func (w *Client) Stat(name string) (fs.FileInfo, error) {
+	info, err := w.UpYun.GetInfo(upyunPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", name, wrapErr(err))
+	}
+	p := fileop.CleanPath(name)
+	info.Name = path.Base(p)
+	return fileInfo{FileInfo: info, path: p}, nil
+}
+
+func (w *Client) Remove(remote string) error {
+	err := wrapErr(w.UpYun.Delete(&upyun.DeleteObjectConfig{Path: upyunPath(remote)}))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove %s: %w", remote, err)
+	}
+	return nil
+}
+
+// RemoveBatch removes the files one by one, upyun has no batch deletion.
+func (w *Client) RemoveBatch(remotes []string) []error {
+	var errs []error
+	for i, remote := range remotes {
+		if err := w.Remove(remote); err != nil {
+			if errs == nil {
+				errs = make([]error, len(remotes))
+			}
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+// Copy copies src to dst server side.
+func (w *Client) Copy(src, dst string) error {
+	return wrapErr(w.UpYun.Copy(&upyun.CopyObjectConfig{
+		SrcPath:  upyunPath(src),
+		DestPath: upyunPath(dst),
+	}))
+}
+
+// Move moves src to dst server side.
+func (w *Client) Move(src, dst string) error {
+	return wrapErr(w.UpYun.Move(&upyun.MoveObjectConfig{
+		SrcPath:  upyunPath(src),
+		DestPath: upyunPath(dst),
+	}))
+}
+
 func (w *Client) Close() error {
 	return nil
 }
+
+// upyunPath converts name to the rooted path expected by the upyun API.
+func upyunPath(name string) string {
+	return "/" + fileop.ObjectKey(name)
+}
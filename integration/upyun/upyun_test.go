@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/fileoptest"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -75,3 +76,19 @@ func (s *WrapTestSuite) TestReaddirnames() {
 
 	s.T().Log("line:", cntLine, ", cost:", time.Since(start))
 }
+
+func (s *WrapTestSuite) TestPathModel() {
+	if s.w == nil {
+		s.T().Skip("require env UPYUN_BUCKET for test")
+	}
+
+	fileoptest.TestPathModel(s.T(), s.w, s.w, "/fileop-test/path_model")
+}
+
+func (s *WrapTestSuite) TestRemove() {
+	if s.w == nil {
+		s.T().Skip("require env UPYUN_BUCKET for test")
+	}
+
+	fileoptest.TestRemove(s.T(), s.w, "/fileop-test/remove")
+}
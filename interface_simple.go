@@ -2,6 +2,7 @@ package fileop
 
 import (
 	"io"
+	"time"
 )
 
 // FileSystemSimple combines basic source reader and target uploader interfaces.
@@ -14,17 +15,53 @@ type FileSystemSimple interface {
 // and content type support for object storage systems.
 type FileSystemSimpleBucket interface {
 	FileSystemSimple
+	IOptionsUploader
 	Bucket(name string) FileSystemSimpleBucket
 	PutStreamWithContentType(reader io.Reader, remote string, contentType string) error
 }
 
+// PutOptions holds object properties set on upload. Empty fields keep the
+// backend default; the content type is guessed from the extension when
+// empty. Object stores ignore the properties they do not support; file
+// systems fail with ErrUnsupported on properties they can not keep.
+type PutOptions struct {
+	ContentType        string
+	ContentEncoding    string
+	CacheControl       string
+	ContentDisposition string
+	Metadata           map[string]string // user metadata, keys without vendor prefix
+	StorageClass       string            // e.g. "STANDARD", "WARM", "COLD"
+	ACL                string            // canned ACL, e.g. "private", "public-read"
+}
+
+// IOptionsUploader provides uploads with object properties.
+type IOptionsUploader interface {
+	PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error
+}
+
 // ITargetUploader provides upload operations for target file systems.
+//
+// Exist reports false on any failure. ExistE returns false with a nil error
+// only when remote does not exist, and the failure otherwise, e.g. on a
+// timeout or missing permission.
 type ITargetUploader interface {
 	io.Closer
+	Stater
 	Put(local, remote string) error
 	PutStream(reader io.Reader, remote string) error
 	PutEmpty(remote string) error
 	Exist(remote string) bool
+	ExistE(remote string) (bool, error)
+	IRemover
+}
+
+// IRemover provides removal operations for target file systems. Removing a
+// missing file is not an error. RemoveBatch returns nil when every remote
+// was removed, and otherwise one error per remote in the same order, nil
+// for the removed ones.
+type IRemover interface {
+	Remove(remote string) error
+	RemoveBatch(remotes []string) []error
 }
 
 // ISourceLister provides directory listing operations for source file systems.
@@ -38,3 +75,10 @@ type ISourceReader interface {
 	ISourceLister
 	Reader
 }
+
+// Presigner is implemented by object stores that hand out temporary URLs,
+// valid for ttl, to download or upload a single object without credentials.
+type Presigner interface {
+	PresignGet(name string, ttl time.Duration) (string, error)
+	PresignPut(name string, ttl time.Duration) (string, error)
+}
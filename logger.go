@@ -0,0 +1,107 @@
+package fileop
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+// LogOptions configures WithLoggerOptions.
+type LogOptions struct {
+	Level   slog.Level // level of successful operations; failures use slog.LevelError
+	Backend string     // logged as "backend" when set
+
+	// Sample logs only the first and then every n-th successful call of an
+	// operation. Nil uses DefaultLogSample, a value below 2 logs every call.
+	// Failures are always logged.
+	Sample map[Op]int
+}
+
+// DefaultLogSample samples the high-volume metadata and read operations.
+var DefaultLogSample = map[Op]int{
+	OpOpen:  100,
+	OpStat:  100,
+	OpExist: 100,
+}
+
+// WithLogger returns fsys with every operation logged at level, or at
+// slog.LevelError when it fails, using DefaultLogSample. See
+// WithLoggerOptions.
+func WithLogger[T any](fsys T, logger *slog.Logger, level slog.Level) (T, error) {
+	return WithLoggerOptions(fsys, logger, LogOptions{Level: level})
+}
+
+// WithLoggerOptions returns fsys with every operation logged with its op,
+// path, duration, bytes and error. Open and Create are logged when the
+// stream is closed, so errors of background downloads show up there.
+// Credentials in paths and error messages, such as URL user info and
+// signature or token query parameters, are redacted. See WithRetry for the
+// meaning of T.
+func WithLoggerOptions[T any](fsys T, logger *slog.Logger, opts LogOptions) (T, error) {
+	if opts.Sample == nil {
+		opts.Sample = DefaultLogSample
+	}
+	counters := make(map[Op]*atomic.Uint64, len(opts.Sample))
+	for op, n := range opts.Sample {
+		if n > 1 {
+			counters[op] = new(atomic.Uint64)
+		}
+	}
+
+	return WithObserver(fsys, ObserverFunc(func(op Op, name string) func(n int64, err error) {
+		start := time.Now()
+		return func(n int64, err error) {
+			level := opts.Level
+			sample := 0
+			if err != nil {
+				level = slog.LevelError
+			} else if counter := counters[op]; counter != nil {
+				sample = opts.Sample[op]
+				if (counter.Add(1)-1)%uint64(sample) != 0 {
+					return
+				}
+			}
+			ctx := context.Background()
+			if !logger.Enabled(ctx, level) {
+				return
+			}
+
+			attrs := make([]slog.Attr, 0, 7)
+			if opts.Backend != "" {
+				attrs = append(attrs, slog.String("backend", opts.Backend))
+			}
+			attrs = append(attrs,
+				slog.String("op", string(op)),
+				slog.String("path", Redact(name)),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int64("bytes", n),
+			)
+			if err != nil {
+				attrs = append(attrs,
+					slog.String("error", Redact(err.Error())),
+					slog.String("error_class", ErrorClass(err)),
+				)
+			}
+			if sample > 1 {
+				attrs = append(attrs, slog.Int("sample", sample))
+			}
+			logger.LogAttrs(ctx, level, "fileop "+string(op), attrs...)
+		}
+	}))
+}
+
+var (
+	redactUserInfo = regexp.MustCompile(`(://)[^/@\s]+@`)
+	redactParam    = regexp.MustCompile(`(?i)((?:signature|token|secret|password|passwd|credential|access_?key(?:_?id)?|security-token|_upt|x-amz-[a-z-]*)=)[^&\s"']+`)
+	redactHeader   = regexp.MustCompile(`(?i)((?:authorization|x-amz-security-token|x-obs-security-token)["']?\s*[:=]\s*["']?)[^"'\s,}]+(?:\s+[^"'\s,}]+)?`)
+)
+
+// Redact masks credentials in s: user info of URLs, query parameters such
+// as Signature, token or AccessKeyId, and authorization headers.
+func Redact(s string) string {
+	s = redactUserInfo.ReplaceAllString(s, "${1}***@")
+	s = redactParam.ReplaceAllString(s, "${1}***")
+	return redactHeader.ReplaceAllString(s, "${1}***")
+}
@@ -0,0 +1,354 @@
+package fileop
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetaCacheOptions configures WithMetaCache. A zero TTL disables caching of
+// the respective results.
+type MetaCacheOptions struct {
+	StatTTL     time.Duration // Stat, Exist and ExistE of existing files
+	ListTTL     time.Duration // Readdir and Readdirnames
+	NegativeTTL time.Duration // Stat and ExistE of missing files
+}
+
+// MetaCache holds the cached metadata of a file system wrapped with
+// WithMetaCache.
+type MetaCache struct {
+	opts MetaCacheOptions
+
+	mu      sync.Mutex
+	entries map[metaKey]metaEntry
+	gen     uint64 // incremented by every invalidation
+	purgeAt int    // purge expired entries at this size
+}
+
+type metaKind int
+
+const (
+	metaStat metaKind = iota
+	metaExist
+	metaReaddir
+	metaReaddirnames
+)
+
+type metaKey struct {
+	kind metaKind
+	name string
+	n    int
+}
+
+type metaEntry struct {
+	expires time.Time
+	info    fs.FileInfo
+	infos   []fs.FileInfo
+	names   []string
+	exist   bool
+	err     error
+}
+
+// WithMetaCache returns fsys with the results of Stat, Exist, ExistE,
+// Readdir and Readdirnames cached, and the cache to invalidate entries for
+// changes made by other clients. Not-found results are cached for
+// NegativeTTL, other errors never. Writes through the returned value
+// invalidate the written paths and the listings of their parent
+// directories. Buckets of the returned value get caches of their own,
+// invalidated by their own writes only. See WithRetry for the meaning of T.
+func WithMetaCache[T any](fsys T, opts MetaCacheOptions) (T, *MetaCache, error) {
+	c := &MetaCache{opts: opts, entries: make(map[metaKey]metaEntry), purgeAt: 1024}
+	m, err := wrapAs[T](newMetaCacheFS(fsys, c))
+	if err != nil {
+		return m, nil, err
+	}
+	return m, c, nil
+}
+
+// Invalidate drops the cached entries of all paths starting with prefix,
+// in the form of ObjectKey, and the listings of the directories containing
+// prefix. Like on object stores "/a" and "a" are the same path. An empty
+// prefix or "/" drops everything.
+func (c *MetaCache) Invalidate(prefix string) {
+	prefix = ObjectKey(prefix)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key := range c.entries {
+		if strings.HasPrefix(key.name, prefix) || (key.kind >= metaReaddir && isKeyAncestor(key.name, prefix)) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// invalidatePath drops the entries of name and the listings of its
+// ancestors, which may change when name or an implicit directory appears.
+func (c *MetaCache) invalidatePath(name string) {
+	name = ObjectKey(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key := range c.entries {
+		switch key.kind {
+		case metaStat, metaExist:
+			if key.name == name {
+				delete(c.entries, key)
+			}
+		case metaReaddir, metaReaddirnames:
+			if key.name == name || isKeyAncestor(key.name, name) {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
+
+// isAncestor reports whether dir contains name, both cleaned.
+func isAncestor(dir, name string) bool {
+	switch dir {
+	case "/":
+		return strings.HasPrefix(name, "/") && name != "/"
+	case ".":
+		return !strings.HasPrefix(name, "/") && name != "."
+	}
+	return strings.HasPrefix(name, dir+"/")
+}
+
+// isKeyAncestor is isAncestor for paths in the form of ObjectKey.
+func isKeyAncestor(dir, name string) bool {
+	return isAncestor("/"+dir, "/"+name)
+}
+
+func (c *MetaCache) get(key metaKey) (metaEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !time.Now().Before(e.expires) {
+		return metaEntry{}, false
+	}
+	return e, true
+}
+
+// generation returns the invalidation counter, to be passed to set.
+func (c *MetaCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// set stores e for ttl unless an invalidation happened since gen, which
+// could make e outdated.
+func (c *MetaCache) set(key metaKey, e metaEntry, ttl time.Duration, gen uint64) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return
+	}
+	if len(c.entries) >= c.purgeAt {
+		now := time.Now()
+		for k, old := range c.entries {
+			if !now.Before(old.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.purgeAt = max(1024, 2*len(c.entries))
+	}
+	e.expires = time.Now().Add(ttl)
+	c.entries[key] = e
+}
+
+type metaCacheFS struct {
+	forwarder
+	cache *MetaCache
+}
+
+func newMetaCacheFS(fsys any, cache *MetaCache) *metaCacheFS {
+	return &metaCacheFS{
+		forwarder: forwarder{
+			fsys: fsys,
+			rewrap: func(fsys any) any {
+				// buckets have paths of their own
+				c := &MetaCache{opts: cache.opts, entries: make(map[metaKey]metaEntry), purgeAt: 1024}
+				return newMetaCacheFS(fsys, c)
+			},
+		},
+		cache: cache,
+	}
+}
+
+func (m *metaCacheFS) Stat(name string) (fs.FileInfo, error) {
+	key := metaKey{kind: metaStat, name: ObjectKey(name)}
+	if e, ok := m.cache.get(key); ok {
+		return e.info, e.err
+	}
+	gen := m.cache.generation()
+	info, err := m.forwarder.Stat(name)
+	switch {
+	case err == nil:
+		m.cache.set(key, metaEntry{info: info}, m.cache.opts.StatTTL, gen)
+	case errors.Is(err, fs.ErrNotExist):
+		m.cache.set(key, metaEntry{err: err}, m.cache.opts.NegativeTTL, gen)
+	}
+	return info, err
+}
+
+func (m *metaCacheFS) ExistE(remote string) (bool, error) {
+	key := metaKey{kind: metaExist, name: ObjectKey(remote)}
+	if e, ok := m.cache.get(key); ok {
+		return e.exist, nil
+	}
+	gen := m.cache.generation()
+	exist, err := m.forwarder.ExistE(remote)
+	switch {
+	case err != nil:
+	case exist:
+		m.cache.set(key, metaEntry{exist: true}, m.cache.opts.StatTTL, gen)
+	default:
+		m.cache.set(key, metaEntry{}, m.cache.opts.NegativeTTL, gen)
+	}
+	return exist, err
+}
+
+// Exist uses ExistE when fsys implements it. Otherwise only existing files
+// are cached, as false may stand for a failure.
+func (m *metaCacheFS) Exist(remote string) bool {
+	if _, ok := As[interface {
+		ExistE(remote string) (bool, error)
+	}](m.fsys); ok {
+		exist, _ := m.ExistE(remote)
+		return exist
+	}
+	key := metaKey{kind: metaExist, name: ObjectKey(remote)}
+	if e, ok := m.cache.get(key); ok {
+		return e.exist
+	}
+	gen := m.cache.generation()
+	exist := m.forwarder.Exist(remote)
+	if exist {
+		m.cache.set(key, metaEntry{exist: true}, m.cache.opts.StatTTL, gen)
+	}
+	return exist
+}
+
+func (m *metaCacheFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	key := metaKey{kind: metaReaddir, name: ObjectKey(dirname), n: n}
+	if e, ok := m.cache.get(key); ok {
+		return append([]fs.FileInfo(nil), e.infos...), nil
+	}
+	gen := m.cache.generation()
+	infos, err := m.forwarder.Readdir(dirname, n)
+	if err == nil {
+		m.cache.set(key, metaEntry{infos: append([]fs.FileInfo(nil), infos...)}, m.cache.opts.ListTTL, gen)
+	}
+	return infos, err
+}
+
+func (m *metaCacheFS) Readdirnames(dirname string, n int) ([]string, error) {
+	key := metaKey{kind: metaReaddirnames, name: ObjectKey(dirname), n: n}
+	if e, ok := m.cache.get(key); ok {
+		return append([]string(nil), e.names...), nil
+	}
+	gen := m.cache.generation()
+	names, err := m.forwarder.Readdirnames(dirname, n)
+	if err == nil {
+		m.cache.set(key, metaEntry{names: append([]string(nil), names...)}, m.cache.opts.ListTTL, gen)
+	}
+	return names, err
+}
+
+func (m *metaCacheFS) Mkdir(dirname string, perm fs.FileMode) error {
+	defer m.cache.invalidatePath(dirname)
+	return m.forwarder.Mkdir(dirname, perm)
+}
+
+func (m *metaCacheFS) MkdirAll(dirname string, perm fs.FileMode) error {
+	defer m.cache.invalidatePath(dirname)
+	return m.forwarder.MkdirAll(dirname, perm)
+}
+
+// Create invalidates name when the file is created and again when it is
+// closed, as the size changes while writing.
+func (m *metaCacheFS) Create(name string) (io.WriteCloser, error) {
+	defer m.cache.invalidatePath(name)
+	wt, err := m.forwarder.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &invalidatingWriter{WriteCloser: wt, invalidate: func() { m.cache.invalidatePath(name) }}, nil
+}
+
+func (m *metaCacheFS) Rename(oldPath, newPath string) error {
+	defer m.cache.Invalidate(newPath)
+	defer m.cache.Invalidate(oldPath)
+	return m.forwarder.Rename(oldPath, newPath)
+}
+
+func (m *metaCacheFS) Remove(name string) error {
+	defer m.cache.invalidatePath(name)
+	return m.forwarder.Remove(name)
+}
+
+func (m *metaCacheFS) RemoveAll(name string) error {
+	defer m.cache.Invalidate(name)
+	return m.forwarder.RemoveAll(name)
+}
+
+func (m *metaCacheFS) RemoveBatch(remotes []string) []error {
+	defer func() {
+		for _, remote := range remotes {
+			m.cache.invalidatePath(remote)
+		}
+	}()
+	return m.forwarder.RemoveBatch(remotes)
+}
+
+func (m *metaCacheFS) Put(local, remote string) error {
+	defer m.cache.invalidatePath(remote)
+	return m.forwarder.Put(local, remote)
+}
+
+func (m *metaCacheFS) PutStream(reader io.Reader, remote string) error {
+	defer m.cache.invalidatePath(remote)
+	return m.forwarder.PutStream(reader, remote)
+}
+
+func (m *metaCacheFS) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	defer m.cache.invalidatePath(remote)
+	return m.forwarder.PutStreamWithOptions(reader, remote, opts)
+}
+
+func (m *metaCacheFS) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	defer m.cache.invalidatePath(remote)
+	return m.forwarder.PutStreamWithContentType(reader, remote, contentType)
+}
+
+func (m *metaCacheFS) PutEmpty(remote string) error {
+	defer m.cache.invalidatePath(remote)
+	return m.forwarder.PutEmpty(remote)
+}
+
+func (m *metaCacheFS) Copy(src, dst string) error {
+	defer m.cache.Invalidate(dst)
+	return m.forwarder.Copy(src, dst)
+}
+
+func (m *metaCacheFS) Move(src, dst string) error {
+	defer m.cache.Invalidate(dst)
+	defer m.cache.Invalidate(src)
+	return m.forwarder.Move(src, dst)
+}
+
+type invalidatingWriter struct {
+	io.WriteCloser
+	invalidate func()
+}
+
+func (w *invalidatingWriter) Close() error {
+	defer w.invalidate()
+	return w.WriteCloser.Close()
+}
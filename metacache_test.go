@@ -0,0 +1,119 @@
+package fileop_test
+
+import (
+	"io/fs"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// countingFS counts the metadata calls reaching the backend.
+type countingFS struct {
+	*afero.Handler
+	stats, lists atomic.Int64
+}
+
+func (c *countingFS) Stat(name string) (fs.FileInfo, error) {
+	c.stats.Add(1)
+	return c.Handler.Stat(name)
+}
+
+func (c *countingFS) Readdirnames(dirname string, n int) ([]string, error) {
+	c.lists.Add(1)
+	return c.Handler.Readdirnames(dirname, n)
+}
+
+func TestWithMetaCache(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	backend := &countingFS{Handler: mfs}
+	fsys, cache, err := fileop.WithMetaCache[fileop.FileSystem](backend, fileop.MetaCacheOptions{
+		StatTTL:     time.Minute,
+		ListTTL:     time.Minute,
+		NegativeTTL: time.Minute,
+	})
+	assert.NoError(err)
+
+	// negative caching
+	for range 2 {
+		_, err = fsys.Stat("/d/a.txt")
+		assert.ErrorIs(err, fs.ErrNotExist)
+	}
+	assert.EqualValues(1, backend.stats.Load())
+
+	// writes through the wrapper invalidate the file and parent listings
+	names, err := fsys.Readdirnames("/", 0)
+	assert.NoError(err)
+	assert.Empty(names)
+	wt, err := fileop.NewFileWriter(fsys, "/d/a.txt", 0, fileop.NONE)
+	assert.NoError(err)
+	assert.NoError(wt.Close())
+
+	info, err := fsys.Stat("/d/a.txt")
+	assert.NoError(err)
+	assert.Equal("a.txt", info.Name())
+	names, err = fsys.Readdirnames("/", 0)
+	assert.NoError(err)
+	assert.Equal([]string{"d"}, names)
+	_, err = fsys.Stat("/d//a.txt")
+	assert.NoError(err)
+	statCalls, listCalls := backend.stats.Load(), backend.lists.Load()
+
+	// changes by others are seen after Invalidate
+	assert.NoError(mfs.Remove("/d/a.txt"))
+	_, err = fsys.Stat("/d/a.txt")
+	assert.NoError(err, "served from cache")
+	cache.Invalidate("/d")
+	_, err = fsys.Stat("/d/a.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+	_, err = fsys.Readdirnames("/", 0)
+	assert.NoError(err)
+	assert.Equal(statCalls+1, backend.stats.Load())
+	assert.Equal(listCalls+1, backend.lists.Load(), "parent listing invalidated")
+}
+
+func TestWithMetaCachePathSpellings(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	// like object stores, the backend treats "/a" and "a" as the same path
+	backend, err := fileop.Sub[fileop.FileSystem](mfs, "/")
+	assert.NoError(err)
+	fsys, cache, err := fileop.WithMetaCache(backend, fileop.MetaCacheOptions{
+		StatTTL:     time.Minute,
+		ListTTL:     time.Minute,
+		NegativeTTL: time.Minute,
+	})
+	assert.NoError(err)
+
+	_, err = fsys.Stat("/a")
+	assert.ErrorIs(err, fs.ErrNotExist)
+	names, err := fsys.Readdirnames("/", 0)
+	assert.NoError(err)
+	assert.Empty(names)
+
+	// a write through one spelling invalidates the other
+	wt, err := fileop.NewFileWriter(fsys, "a", 0, fileop.NONE)
+	assert.NoError(err)
+	assert.NoError(wt.Close())
+	_, err = fsys.Stat("/a")
+	assert.NoError(err)
+	names, err = fsys.Readdirnames("", 0)
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, names)
+
+	assert.NoError(mfs.Remove("/a"))
+	cache.Invalidate("a")
+	_, err = fsys.Stat("/a")
+	assert.ErrorIs(err, fs.ErrNotExist)
+}
@@ -0,0 +1,26 @@
+package fileop
+
+import "time"
+
+// MetricsHook receives the measurements of WithMetrics, so that any metrics
+// stack can be plugged in; see package fileopprom for Prometheus. It must be
+// safe for concurrent use.
+//
+// ObserveOp is called once per operation with the backend label given to
+// WithMetrics, the duration, the bytes read or written as described for
+// Observer, and the resulting error, which ErrorClass turns into a label.
+type MetricsHook interface {
+	ObserveOp(backend string, op Op, duration time.Duration, bytes int64, err error)
+}
+
+// WithMetrics returns fsys with every operation measured and reported to
+// hook under the backend label, e.g. the simplefs mode. See WithRetry for
+// the meaning of T.
+func WithMetrics[T any](fsys T, backend string, hook MetricsHook) (T, error) {
+	return WithObserver(fsys, ObserverFunc(func(op Op, _ string) func(n int64, err error) {
+		start := time.Now()
+		return func(n int64, err error) {
+			hook.ObserveOp(backend, op, time.Since(start), n, err)
+		}
+	}))
+}
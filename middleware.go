@@ -0,0 +1,305 @@
+package fileop
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// Unwrapper is implemented by the middlewares of this package, returning
+// the file system they wrap.
+type Unwrapper interface {
+	Unwrap() any
+}
+
+// As returns fsys as the optional interface T, such as Copier, Presigner or
+// PrefixLister, if fsys implements it. Middlewares implement every method
+// and fail with ErrUnsupported where the wrapped file system lacks one, so
+// As also requires every file system below fsys in the Unwrap chain to
+// implement T. Use it instead of a type assertion to detect capabilities.
+func As[T any](fsys any) (T, bool) {
+	var zero T
+	t, ok := fsys.(T)
+	if !ok {
+		return zero, false
+	}
+	for u, ok := fsys.(Unwrapper); ok; u, ok = fsys.(Unwrapper) {
+		fsys = u.Unwrap()
+		if _, ok := fsys.(T); !ok {
+			return zero, false
+		}
+	}
+	return t, true
+}
+
+// forwarder implements every method of the fileop interfaces by forwarding
+// to fsys. Middlewares embed it and override the methods they decorate;
+// methods fsys lacks return ErrUnsupported.
+//
+// The middleware constructors are generic over the interface type of their
+// argument, e.g. FileSystem, FileSystemWithCloser, ISourceReader,
+// ITargetUploader, FileSystemSimple or FileSystemSimpleBucket, and return
+// the same interface type. Optional interfaces such as Copier or Presigner
+// are forwarded as well; callers detect them with As.
+type forwarder struct {
+	fsys any
+	// rewrap applies the same middleware to a bucket returned by fsys.
+	rewrap func(fsys any) any
+}
+
+// Forwarder is the base of the middlewares of this package for middlewares
+// outside of it, such as fault injection in tests. Embed it, override the
+// methods to decorate and call the embedded ones to forward. Like the
+// middlewares of this package it implements Unwrapper, so As sees through
+// it.
+type Forwarder struct {
+	forwarder
+}
+
+// NewForwarder returns a Forwarder to fsys. rewrap applies the middleware to
+// the buckets returned by Bucket; nil leaves them unwrapped.
+func NewForwarder(fsys any, rewrap func(fsys any) any) Forwarder {
+	if rewrap == nil {
+		rewrap = func(fsys any) any { return fsys }
+	}
+	return Forwarder{forwarder{fsys: fsys, rewrap: rewrap}}
+}
+
+// wrapAs returns the middleware m as the interface type T, failing for
+// concrete types and interfaces with methods the middleware lacks.
+func wrapAs[T any](m any) (T, error) {
+	t, ok := m.(T)
+	if !ok {
+		return t, fmt.Errorf("middleware does not implement %v: %w", reflect.TypeFor[T](), ErrUnsupported)
+	}
+	return t, nil
+}
+
+// Unwrap returns the wrapped file system.
+func (f forwarder) Unwrap() any {
+	return f.fsys
+}
+
+func (f forwarder) unsupported(op, name string) error {
+	return fmt.Errorf("%s %s: %w", op, name, ErrUnsupported)
+}
+
+func (f forwarder) Open(name string) (io.ReadCloser, error) {
+	if r, ok := f.fsys.(Reader); ok {
+		return r.Open(name)
+	}
+	return nil, f.unsupported("open", name)
+}
+
+func (f forwarder) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	if r, ok := f.fsys.(DirReader); ok {
+		return r.Readdir(dirname, n)
+	}
+	return nil, f.unsupported("readdir", dirname)
+}
+
+func (f forwarder) Readdirnames(dirname string, n int) ([]string, error) {
+	if r, ok := f.fsys.(DirReader); ok {
+		return r.Readdirnames(dirname, n)
+	}
+	return nil, f.unsupported("readdirnames", dirname)
+}
+
+func (f forwarder) Stat(name string) (fs.FileInfo, error) {
+	if s, ok := f.fsys.(Stater); ok {
+		return s.Stat(name)
+	}
+	return nil, f.unsupported("stat", name)
+}
+
+func (f forwarder) Walk(root string, walkFn filepath.WalkFunc) error {
+	if w, ok := f.fsys.(Walker); ok {
+		return w.Walk(root, walkFn)
+	}
+	return f.unsupported("walk", root)
+}
+
+func (f forwarder) Mkdir(dirname string, perm fs.FileMode) error {
+	if d, ok := f.fsys.(DirCreator); ok {
+		return d.Mkdir(dirname, perm)
+	}
+	return f.unsupported("mkdir", dirname)
+}
+
+func (f forwarder) MkdirAll(dirname string, perm fs.FileMode) error {
+	if d, ok := f.fsys.(DirCreator); ok {
+		return d.MkdirAll(dirname, perm)
+	}
+	return f.unsupported("mkdir", dirname)
+}
+
+func (f forwarder) Create(name string) (io.WriteCloser, error) {
+	if w, ok := f.fsys.(interface {
+		Create(name string) (io.WriteCloser, error)
+	}); ok {
+		return w.Create(name)
+	}
+	return nil, f.unsupported("create", name)
+}
+
+func (f forwarder) Rename(oldPath, newPath string) error {
+	if w, ok := f.fsys.(interface {
+		Rename(oldPath, newPath string) error
+	}); ok {
+		return w.Rename(oldPath, newPath)
+	}
+	return f.unsupported("rename", oldPath)
+}
+
+func (f forwarder) Remove(name string) error {
+	if c, ok := f.fsys.(interface{ Remove(name string) error }); ok {
+		return c.Remove(name)
+	}
+	return f.unsupported("remove", name)
+}
+
+func (f forwarder) RemoveAll(name string) error {
+	if c, ok := f.fsys.(interface{ RemoveAll(name string) error }); ok {
+		return c.RemoveAll(name)
+	}
+	return f.unsupported("remove", name)
+}
+
+func (f forwarder) RemoveBatch(remotes []string) []error {
+	if r, ok := f.fsys.(IRemover); ok {
+		return r.RemoveBatch(remotes)
+	}
+	if len(remotes) == 0 {
+		return nil
+	}
+	errs := make([]error, len(remotes))
+	for i, remote := range remotes {
+		errs[i] = f.unsupported("remove", remote)
+	}
+	return errs
+}
+
+func (f forwarder) Close() error {
+	if c, ok := f.fsys.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (f forwarder) Put(local, remote string) error {
+	if u, ok := f.fsys.(interface {
+		Put(local, remote string) error
+	}); ok {
+		return u.Put(local, remote)
+	}
+	return f.unsupported("put", remote)
+}
+
+func (f forwarder) PutStream(reader io.Reader, remote string) error {
+	if u, ok := f.fsys.(interface {
+		PutStream(reader io.Reader, remote string) error
+	}); ok {
+		return u.PutStream(reader, remote)
+	}
+	return f.unsupported("put", remote)
+}
+
+func (f forwarder) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	if u, ok := f.fsys.(IOptionsUploader); ok {
+		return u.PutStreamWithOptions(reader, remote, opts)
+	}
+	return f.unsupported("put", remote)
+}
+
+func (f forwarder) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	if u, ok := f.fsys.(interface {
+		PutStreamWithContentType(reader io.Reader, remote string, contentType string) error
+	}); ok {
+		return u.PutStreamWithContentType(reader, remote, contentType)
+	}
+	return f.unsupported("put", remote)
+}
+
+func (f forwarder) PutEmpty(remote string) error {
+	if u, ok := f.fsys.(interface{ PutEmpty(remote string) error }); ok {
+		return u.PutEmpty(remote)
+	}
+	return f.unsupported("put", remote)
+}
+
+func (f forwarder) Exist(remote string) bool {
+	if u, ok := f.fsys.(interface{ Exist(remote string) bool }); ok {
+		return u.Exist(remote)
+	}
+	return false
+}
+
+func (f forwarder) ExistE(remote string) (bool, error) {
+	if u, ok := f.fsys.(interface {
+		ExistE(remote string) (bool, error)
+	}); ok {
+		return u.ExistE(remote)
+	}
+	return false, f.unsupported("exist", remote)
+}
+
+// Bucket wraps the bucket with the same middleware. Without buckets in
+// fsys every method of the returned bucket fails with ErrUnsupported.
+func (f forwarder) Bucket(name string) FileSystemSimpleBucket {
+	b, ok := f.fsys.(interface {
+		Bucket(name string) FileSystemSimpleBucket
+	})
+	if !ok {
+		return forwarder{}
+	}
+	bucket, err := wrapAs[FileSystemSimpleBucket](f.rewrap(b.Bucket(name)))
+	if err != nil {
+		return forwarder{}
+	}
+	return bucket
+}
+
+func (f forwarder) Copy(src, dst string) error {
+	if c, ok := f.fsys.(Copier); ok {
+		return c.Copy(src, dst)
+	}
+	return f.unsupported("copy", src)
+}
+
+func (f forwarder) Move(src, dst string) error {
+	if c, ok := f.fsys.(Copier); ok {
+		return c.Move(src, dst)
+	}
+	return f.unsupported("move", src)
+}
+
+func (f forwarder) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	if l, ok := f.fsys.(PrefixLister); ok {
+		return l.ReaddirPrefix(dirname, prefix, n)
+	}
+	return nil, f.unsupported("readdir", dirname)
+}
+
+func (f forwarder) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	if l, ok := f.fsys.(PrefixLister); ok {
+		return l.ReaddirnamesPrefix(dirname, prefix, n)
+	}
+	return nil, f.unsupported("readdirnames", dirname)
+}
+
+func (f forwarder) PresignGet(name string, ttl time.Duration) (string, error) {
+	if p, ok := f.fsys.(Presigner); ok {
+		return p.PresignGet(name, ttl)
+	}
+	return "", f.unsupported("presign", name)
+}
+
+func (f forwarder) PresignPut(name string, ttl time.Duration) (string, error) {
+	if p, ok := f.fsys.(Presigner); ok {
+		return p.PresignPut(name, ttl)
+	}
+	return "", f.unsupported("presign", name)
+}
@@ -0,0 +1,96 @@
+package fileop_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// copierFS is a file system with server-side Copy and Move, counting their
+// calls.
+type copierFS struct {
+	*afero.Handler
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newCopierFS(t *testing.T) *copierFS {
+	mfs, err := afero.New(afero.Memory)
+	require.NoError(t, err)
+	return &copierFS{Handler: mfs, calls: make(map[string]int)}
+}
+
+func (c *copierFS) count(op string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[op]++
+}
+
+func (c *copierFS) Calls(op string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[op]
+}
+
+func (c *copierFS) Copy(src, dst string) error {
+	c.count("copy")
+	return fileop.CopyStream(c.Handler, src, c.Handler, dst)
+}
+
+func (c *copierFS) Move(src, dst string) error {
+	c.count("move")
+	return c.Handler.Rename(src, dst)
+}
+
+func TestAs(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	cfs := newCopierFS(t)
+	fsys, err := fileop.WithRetry[fileop.FileSystem](cfs, fileop.RetryPolicy{})
+	assert.NoError(err)
+	fsys, err = fileop.WithObserver(fsys, fileop.ObserverFunc(func(fileop.Op, string) func(int64, error) {
+		return func(int64, error) {}
+	}))
+	assert.NoError(err)
+	c, ok := fileop.As[fileop.Copier](fsys)
+	assert.True(ok)
+	assert.NoError(fsys.MkdirAll("/d", 0755))
+	writeString(t, fsys, "/d/a.txt", "a")
+	assert.NoError(c.Copy("/d/a.txt", "/d/b.txt"))
+	assert.Equal(1, cfs.Calls("copy"))
+
+	// the wrapper implements Copier, the wrapped afero handler does not
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	fsys, err = fileop.WithRetry[fileop.FileSystem](mfs, fileop.RetryPolicy{})
+	assert.NoError(err)
+	_, ok = fsys.(fileop.Copier)
+	assert.True(ok)
+	_, ok = fileop.As[fileop.Copier](fsys)
+	assert.False(ok)
+	_, ok = fileop.As[fileop.Stater](fsys)
+	assert.True(ok)
+	assert.ErrorIs(fsys.(fileop.Copier).Copy("/a", "/b"), fileop.ErrUnsupported)
+
+	// concrete types and missing buckets fail instead of panicking
+	_, err = fileop.WithRetry(mfs, fileop.RetryPolicy{})
+	assert.ErrorIs(err, fileop.ErrUnsupported)
+	simple, err := fileop.WithRetry[fileop.FileSystemSimpleBucket](fileop.Forward(mfs), fileop.RetryPolicy{})
+	assert.NoError(err)
+	bucket := simple.Bucket("b")
+	_, err = bucket.Stat("/a")
+	assert.ErrorIs(err, fileop.ErrUnsupported)
+	_, err = bucket.Readdir("/", 0)
+	assert.ErrorIs(err, fileop.ErrUnsupported)
+	_, err = bucket.(fileop.Presigner).PresignGet("/a", time.Minute)
+	assert.ErrorIs(err, fileop.ErrUnsupported)
+	_, ok = fileop.As[fileop.Stater](bucket)
+	assert.False(ok)
+}
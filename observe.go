@@ -0,0 +1,422 @@
+package fileop
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// Op names an operation reported to an Observer.
+type Op string
+
+const (
+	OpOpen         Op = "open"
+	OpReaddir      Op = "readdir"
+	OpReaddirnames Op = "readdirnames"
+	OpStat         Op = "stat"
+	OpWalk         Op = "walk"
+	OpMkdir        Op = "mkdir"
+	OpMkdirAll     Op = "mkdir_all"
+	OpCreate       Op = "create"
+	OpRename       Op = "rename"
+	OpRemove       Op = "remove"
+	OpRemoveAll    Op = "remove_all"
+	OpRemoveBatch  Op = "remove_batch"
+	OpPut          Op = "put"
+	OpPutStream    Op = "put_stream"
+	OpPutEmpty     Op = "put_empty"
+	OpExist        Op = "exist"
+	OpCopy         Op = "copy"
+	OpMove         Op = "move"
+	OpPresign      Op = "presign"
+)
+
+// Observer is notified of the operations of a file system wrapped with
+// WithObserver. It must be safe for concurrent use.
+//
+// Begin is called when op starts on name and returns the function called
+// once when it ends, with the bytes transferred and the resulting error.
+// Open and Create end when the returned stream is closed, so the bytes are
+// those read or written through it. Uploads count the bytes read from the
+// given reader; Put from a local file reports 0 bytes.
+type Observer interface {
+	Begin(op Op, name string) (end func(n int64, err error))
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(op Op, name string) (end func(n int64, err error))
+
+func (f ObserverFunc) Begin(op Op, name string) func(n int64, err error) {
+	return f(op, name)
+}
+
+// BucketObserver is an Observer that reports the operations of buckets
+// opened through Bucket(name) of the wrapped file system to the Observer
+// returned by ForBucket, e.g. to annotate them with the bucket name.
+type BucketObserver interface {
+	Observer
+	ForBucket(name string) Observer
+}
+
+// WithObserver returns fsys with every operation reported to obs. It is the
+// building block of the metrics, logging and tracing middlewares; see
+// WithRetry for the meaning of T.
+func WithObserver[T any](fsys T, obs Observer) (T, error) {
+	return wrapAs[T](newObservedFS(fsys, obs))
+}
+
+// ErrorClass returns a short name for the class of err, suitable as a
+// metric label: "" for nil, "not_exist", "permission", "exist",
+// "throttled", "unavailable", "canceled", "timeout", "unsupported" or
+// "other".
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_exist"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission"
+	case errors.Is(err, fs.ErrExist):
+		return "exist"
+	case errors.Is(err, ErrThrottled):
+		return "throttled"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrUnsupported):
+		return "unsupported"
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return "timeout"
+	}
+	return "other"
+}
+
+type observedFS struct {
+	forwarder
+	obs Observer
+}
+
+func newObservedFS(fsys any, obs Observer) *observedFS {
+	return &observedFS{
+		forwarder: forwarder{
+			fsys: fsys,
+			rewrap: func(fsys any) any {
+				return newObservedFS(fsys, obs)
+			},
+		},
+		obs: obs,
+	}
+}
+
+// Bucket wraps the bucket with the Observer for name when obs is a
+// BucketObserver, and with obs otherwise.
+func (o *observedFS) Bucket(name string) FileSystemSimpleBucket {
+	obs := o.obs
+	if b, ok := obs.(BucketObserver); ok {
+		obs = b.ForBucket(name)
+	}
+	f := o.forwarder
+	f.rewrap = func(fsys any) any {
+		return newObservedFS(fsys, obs)
+	}
+	return f.Bucket(name)
+}
+
+func (o *observedFS) Open(name string) (io.ReadCloser, error) {
+	end := o.obs.Begin(OpOpen, name)
+	rd, err := o.forwarder.Open(name)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	return &observedReader{ReadCloser: rd, end: end}, nil
+}
+
+func (o *observedFS) Create(name string) (io.WriteCloser, error) {
+	end := o.obs.Begin(OpCreate, name)
+	wt, err := o.forwarder.Create(name)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	return &observedWriter{WriteCloser: wt, end: end}, nil
+}
+
+func (o *observedFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	end := o.obs.Begin(OpReaddir, dirname)
+	infos, err := o.forwarder.Readdir(dirname, n)
+	end(0, err)
+	return infos, err
+}
+
+func (o *observedFS) Readdirnames(dirname string, n int) ([]string, error) {
+	end := o.obs.Begin(OpReaddirnames, dirname)
+	names, err := o.forwarder.Readdirnames(dirname, n)
+	end(0, err)
+	return names, err
+}
+
+func (o *observedFS) Stat(name string) (fs.FileInfo, error) {
+	end := o.obs.Begin(OpStat, name)
+	info, err := o.forwarder.Stat(name)
+	end(0, err)
+	return info, err
+}
+
+func (o *observedFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	end := o.obs.Begin(OpWalk, root)
+	err := o.forwarder.Walk(root, walkFn)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) Mkdir(dirname string, perm fs.FileMode) error {
+	end := o.obs.Begin(OpMkdir, dirname)
+	err := o.forwarder.Mkdir(dirname, perm)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) MkdirAll(dirname string, perm fs.FileMode) error {
+	end := o.obs.Begin(OpMkdirAll, dirname)
+	err := o.forwarder.MkdirAll(dirname, perm)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) Rename(oldPath, newPath string) error {
+	end := o.obs.Begin(OpRename, oldPath)
+	err := o.forwarder.Rename(oldPath, newPath)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) Remove(name string) error {
+	end := o.obs.Begin(OpRemove, name)
+	err := o.forwarder.Remove(name)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) RemoveAll(name string) error {
+	end := o.obs.Begin(OpRemoveAll, name)
+	err := o.forwarder.RemoveAll(name)
+	end(0, err)
+	return err
+}
+
+// RemoveBatch reports the first error of the batch.
+func (o *observedFS) RemoveBatch(remotes []string) []error {
+	end := o.obs.Begin(OpRemoveBatch, "")
+	errs := o.forwarder.RemoveBatch(remotes)
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			first = err
+			break
+		}
+	}
+	end(0, first)
+	return errs
+}
+
+func (o *observedFS) Put(local, remote string) error {
+	end := o.obs.Begin(OpPut, remote)
+	err := o.forwarder.Put(local, remote)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) PutStream(reader io.Reader, remote string) error {
+	return o.putStream(reader, remote, func(rd io.Reader) error {
+		return o.forwarder.PutStream(rd, remote)
+	})
+}
+
+func (o *observedFS) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	return o.putStream(reader, remote, func(rd io.Reader) error {
+		return o.forwarder.PutStreamWithOptions(rd, remote, opts)
+	})
+}
+
+func (o *observedFS) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	return o.putStream(reader, remote, func(rd io.Reader) error {
+		return o.forwarder.PutStreamWithContentType(rd, remote, contentType)
+	})
+}
+
+func (o *observedFS) putStream(reader io.Reader, remote string, put func(rd io.Reader) error) error {
+	end := o.obs.Begin(OpPutStream, remote)
+	counter := &countingReader{Reader: reader}
+	err := put(counter.reader())
+	end(counter.n, err)
+	return err
+}
+
+func (o *observedFS) PutEmpty(remote string) error {
+	end := o.obs.Begin(OpPutEmpty, remote)
+	err := o.forwarder.PutEmpty(remote)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) Exist(remote string) bool {
+	end := o.obs.Begin(OpExist, remote)
+	exist := o.forwarder.Exist(remote)
+	end(0, nil)
+	return exist
+}
+
+func (o *observedFS) ExistE(remote string) (bool, error) {
+	end := o.obs.Begin(OpExist, remote)
+	exist, err := o.forwarder.ExistE(remote)
+	end(0, err)
+	return exist, err
+}
+
+func (o *observedFS) Copy(src, dst string) error {
+	end := o.obs.Begin(OpCopy, src)
+	err := o.forwarder.Copy(src, dst)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) Move(src, dst string) error {
+	end := o.obs.Begin(OpMove, src)
+	err := o.forwarder.Move(src, dst)
+	end(0, err)
+	return err
+}
+
+func (o *observedFS) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	end := o.obs.Begin(OpReaddir, dirname)
+	infos, err := o.forwarder.ReaddirPrefix(dirname, prefix, n)
+	end(0, err)
+	return infos, err
+}
+
+func (o *observedFS) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	end := o.obs.Begin(OpReaddirnames, dirname)
+	names, err := o.forwarder.ReaddirnamesPrefix(dirname, prefix, n)
+	end(0, err)
+	return names, err
+}
+
+func (o *observedFS) PresignGet(name string, ttl time.Duration) (string, error) {
+	end := o.obs.Begin(OpPresign, name)
+	url, err := o.forwarder.PresignGet(name, ttl)
+	end(0, err)
+	return url, err
+}
+
+func (o *observedFS) PresignPut(name string, ttl time.Duration) (string, error) {
+	end := o.obs.Begin(OpPresign, name)
+	url, err := o.forwarder.PresignPut(name, ttl)
+	end(0, err)
+	return url, err
+}
+
+// countingReader counts the bytes read from Reader.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// reader returns r, keeping io.Seeker of the underlying reader so that
+// replaying middlewares below still rewind it. Seeking resets the count.
+func (r *countingReader) reader() io.Reader {
+	if s, ok := r.Reader.(io.ReadSeeker); ok {
+		return &countingSeeker{countingReader: r, seeker: s}
+	}
+	return r
+}
+
+type countingSeeker struct {
+	*countingReader
+	seeker io.Seeker
+}
+
+func (s *countingSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := s.seeker.Seek(offset, whence)
+	if err == nil && whence == io.SeekStart {
+		s.n = 0
+	}
+	return pos, err
+}
+
+// observedReader ends the observation of Open on Close, with the bytes read
+// and the first read error other than io.EOF, or the close error.
+type observedReader struct {
+	io.ReadCloser
+	end  func(n int64, err error)
+	n    int64
+	err  error
+	done bool
+}
+
+func (r *observedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *observedReader) Close() error {
+	err := r.ReadCloser.Close()
+	if !r.done {
+		r.done = true
+		if r.err == nil {
+			r.err = err
+		}
+		r.end(r.n, r.err)
+	}
+	return err
+}
+
+// observedWriter ends the observation of Create on Close, with the bytes
+// written and the first write or close error.
+type observedWriter struct {
+	io.WriteCloser
+	end  func(n int64, err error)
+	n    int64
+	err  error
+	done bool
+}
+
+func (w *observedWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.n += int64(n)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *observedWriter) Close() error {
+	err := w.WriteCloser.Close()
+	if !w.done {
+		w.done = true
+		if w.err == nil {
+			w.err = err
+		}
+		w.end(w.n, w.err)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package fileop_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+type observation struct {
+	op   fileop.Op
+	name string
+	n    int64
+	err  error
+}
+
+func TestWithObserver(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	var mu sync.Mutex
+	var got []observation
+	obs := fileop.ObserverFunc(func(op fileop.Op, name string) func(n int64, err error) {
+		return func(n int64, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, observation{op, name, n, err})
+		}
+	})
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	fsys, err := fileop.WithObserver[fileop.FileSystem](mfs, obs)
+	assert.NoError(err)
+
+	wt, err := fsys.Create("/a.txt")
+	assert.NoError(err)
+	_, err = wt.Write([]byte("hello"))
+	assert.NoError(err)
+	assert.Empty(got, "create ends on close")
+	assert.NoError(wt.Close())
+
+	rd, err := fsys.Open("/a.txt")
+	assert.NoError(err)
+	_, err = io.ReadAll(rd)
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+	assert.NoError(rd.Close())
+
+	_, err = fsys.Stat("/missing")
+	assert.Error(err)
+
+	assert.Len(got, 3)
+	assert.Equal(observation{fileop.OpCreate, "/a.txt", 5, nil}, got[0])
+	assert.Equal(observation{fileop.OpOpen, "/a.txt", 5, nil}, got[1])
+	assert.Equal(fileop.OpStat, got[2].op)
+	assert.Equal("not_exist", fileop.ErrorClass(got[2].err))
+}
+
+func TestErrorClass(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	for err, want := range map[error]string{
+		nil:                                    "",
+		fmt.Errorf("open: %w", fs.ErrNotExist): "not_exist",
+		fileop.Classify(io.EOF, fileop.ErrThrottled):    "throttled",
+		fmt.Errorf("get: %w", context.DeadlineExceeded): "timeout",
+		fmt.Errorf("copy: %w", fileop.ErrUnsupported):   "unsupported",
+		io.ErrUnexpectedEOF:                             "other",
+	} {
+		assert.Equal(want, fileop.ErrorClass(err), "%v", err)
+	}
+}
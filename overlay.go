@@ -0,0 +1,401 @@
+package fileop
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WhiteoutPrefix starts the names of the files marking deletes of lower
+// layer entries in the top layer of an Overlay.
+const WhiteoutPrefix = ".wh."
+
+// OverlayOptions configures NewOverlay.
+type OverlayOptions struct {
+	// Top receives all writes. It is also the first layer read. Without a
+	// top layer the overlay is read-only and writes fail with ErrReadOnly.
+	Top FileSystem
+	// Lower are the read-only layers below Top, highest first.
+	Lower []ISourceReader
+	// Whiteouts records removes and renames of lower layer entries as
+	// WhiteoutPrefix files in Top. Without whiteouts these operations fail
+	// with ErrReadOnly.
+	Whiteouts bool
+}
+
+// Overlay layers several backends into one file system. Reads resolve from
+// the first layer that has the path, listings merge the entries of all
+// layers, and writes go to the top layer. A whiteout hides the lower layer
+// entries at and below its path, while entries written to the top layer
+// afterwards remain visible.
+type Overlay struct {
+	top   FileSystem
+	lower []ISourceReader
+	opts  OverlayOptions
+}
+
+var (
+	_ FileSystemWithCloser = (*Overlay)(nil)
+	_ ISourceReader        = (*Overlay)(nil)
+)
+
+// NewOverlay creates an overlay of opts.Top over opts.Lower.
+func NewOverlay(opts OverlayOptions) *Overlay {
+	return &Overlay{top: opts.Top, lower: opts.Lower, opts: opts}
+}
+
+// lowerLayers returns the layers below top, or none when name is hidden by
+// a whiteout.
+func (o *Overlay) lowerLayers(name string) ([]ISourceReader, error) {
+	hidden, err := o.whitedOut(name)
+	if err != nil || hidden {
+		return nil, err
+	}
+	return o.lower, nil
+}
+
+func whiteoutPath(name string) string {
+	dir, base := path.Split(CleanPath(name))
+	return path.Join(dir, WhiteoutPrefix+base)
+}
+
+// whitedOut reports whether a whiteout in the top layer hides name or one
+// of its ancestors.
+func (o *Overlay) whitedOut(name string) (bool, error) {
+	if !o.opts.Whiteouts || o.top == nil {
+		return false, nil
+	}
+	for p := CleanPath(name); p != "/" && p != "."; p = path.Dir(p) {
+		_, err := o.top.Stat(whiteoutPath(p))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, fmt.Errorf("stat whiteout %s: %w", p, err)
+		}
+	}
+	return false, nil
+}
+
+// statLayer stats name on a layer, through its Readdir for layers without
+// Stater.
+func statLayer(layer ISourceReader, name string) (fs.FileInfo, error) {
+	if st, ok := As[Stater](layer); ok {
+		return st.Stat(name)
+	}
+	name = CleanPath(name)
+	dir, base := path.Split(name)
+	infos, err := layer.Readdir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.Name() == base {
+			return info, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o *Overlay) Open(name string) (io.ReadCloser, error) {
+	if o.top != nil {
+		rd, err := o.top.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return rd, err
+		}
+	}
+	lower, err := o.lowerLayers(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lower {
+		rd, err := l.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return rd, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o *Overlay) Stat(name string) (fs.FileInfo, error) {
+	if o.top != nil {
+		info, err := o.top.Stat(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return info, err
+		}
+	}
+	lower, err := o.lowerLayers(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lower {
+		info, err := statLayer(l, name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return info, err
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Readdir merges the entries of dirname in all layers. Entries of higher
+// layers shadow those of the same name below, and whiteouts hide entries of
+// lower layers. The result is sorted by name; n > 0 limits its length.
+func (o *Overlay) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	err := o.readdir(dirname, func(info fs.FileInfo) {
+		infos = append(infos, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	if n > 0 && len(infos) > n {
+		infos = infos[:n]
+	}
+	return infos, nil
+}
+
+// Readdirnames is Readdir returning names only.
+func (o *Overlay) Readdirnames(dirname string, n int) ([]string, error) {
+	infos, err := o.Readdir(dirname, n)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, nil
+}
+
+func (o *Overlay) readdir(dirname string, yield func(fs.FileInfo)) error {
+	seen := make(map[string]bool)
+	found := false
+	if o.top != nil {
+		infos, err := o.top.Readdir(dirname, 0)
+		switch {
+		case err == nil:
+			found = true
+			for _, info := range infos {
+				name := info.Name()
+				if strings.HasPrefix(name, WhiteoutPrefix) && o.opts.Whiteouts {
+					seen[strings.TrimPrefix(name, WhiteoutPrefix)] = true
+					continue
+				}
+				seen[name] = true
+				yield(info)
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+	}
+	lower, err := o.lowerLayers(dirname)
+	if err != nil {
+		return err
+	}
+	for _, l := range lower {
+		infos, err := l.Readdir(dirname, 0)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		for _, info := range infos {
+			if !seen[info.Name()] {
+				seen[info.Name()] = true
+				yield(info)
+			}
+		}
+	}
+	if !found {
+		return &fs.PathError{Op: "readdir", Path: dirname, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// Walk walks the merged tree like filepath.Walk.
+func (o *Overlay) Walk(root string, walkFn filepath.WalkFunc) error {
+	return Walk(o, root, walkFn)
+}
+
+// writable returns the top layer, failing when there is none.
+func (o *Overlay) writable(op, name string) (FileSystem, error) {
+	if o.top == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: ErrReadOnly}
+	}
+	return o.top, nil
+}
+
+// inLower reports whether name exists in a layer below top.
+func (o *Overlay) inLower(name string) (bool, error) {
+	lower, err := o.lowerLayers(name)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range lower {
+		_, err := statLayer(l, name)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// clearWhiteout removes the whiteout of a file replaced in the top layer.
+// Whiteouts of directories stay, hiding the lower layer entries of
+// directories created again.
+func (o *Overlay) clearWhiteout(name string) {
+	if o.opts.Whiteouts {
+		_ = o.top.Remove(whiteoutPath(name))
+	}
+}
+
+// whiteout hides the lower layer entries of name.
+func (o *Overlay) whiteout(name string) error {
+	p := whiteoutPath(name)
+	if err := o.top.MkdirAll(path.Dir(p), 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", path.Dir(p), err)
+	}
+	wt, err := o.top.Create(p)
+	if err != nil {
+		return fmt.Errorf("create whiteout %s: %w", p, err)
+	}
+	if err := wt.Close(); err != nil {
+		return fmt.Errorf("close whiteout %s: %w", p, err)
+	}
+	return nil
+}
+
+// Create creates name in the top layer, creating its parent directories
+// there as needed.
+func (o *Overlay) Create(name string) (io.WriteCloser, error) {
+	top, err := o.writable("create", name)
+	if err != nil {
+		return nil, err
+	}
+	if err := top.MkdirAll(path.Dir(CleanPath(name)), 0755); err != nil {
+		return nil, fmt.Errorf("mkdir %s: %w", path.Dir(name), err)
+	}
+	o.clearWhiteout(name)
+	return top.Create(name)
+}
+
+func (o *Overlay) Mkdir(dirname string, perm fs.FileMode) error {
+	top, err := o.writable("mkdir", dirname)
+	if err != nil {
+		return err
+	}
+	if _, err := o.Stat(dirname); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: dirname, Err: fs.ErrExist}
+	}
+	if err := top.MkdirAll(path.Dir(CleanPath(dirname)), 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", path.Dir(dirname), err)
+	}
+	return top.Mkdir(dirname, perm)
+}
+
+func (o *Overlay) MkdirAll(dirname string, perm fs.FileMode) error {
+	top, err := o.writable("mkdir", dirname)
+	if err != nil {
+		return err
+	}
+	return top.MkdirAll(dirname, perm)
+}
+
+// Rename renames within the top layer. Files of lower layers are copied up
+// to newPath and hidden by a whiteout; lower layer directories can not be
+// renamed.
+func (o *Overlay) Rename(oldPath, newPath string) error {
+	top, err := o.writable("rename", oldPath)
+	if err != nil {
+		return err
+	}
+	lower, err := o.inLower(oldPath)
+	if err != nil {
+		return err
+	}
+	if lower && !o.opts.Whiteouts {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: ErrReadOnly}
+	}
+	if err := top.MkdirAll(path.Dir(CleanPath(newPath)), 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", path.Dir(newPath), err)
+	}
+
+	if _, err := top.Stat(oldPath); err == nil {
+		if err := top.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	} else {
+		info, err := o.Stat(oldPath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return &fs.PathError{Op: "rename", Path: oldPath, Err: ErrUnsupported}
+		}
+		if err := copyStream(o, oldPath, top, newPath); err != nil {
+			return err
+		}
+	}
+	if lower {
+		return o.whiteout(oldPath)
+	}
+	return nil
+}
+
+// Remove removes name from the top layer and hides it in the lower layers.
+func (o *Overlay) Remove(name string) error {
+	return o.remove("remove", name, func(top FileSystem) error { return top.Remove(name) })
+}
+
+// RemoveAll removes name and its entries from the top layer and hides them
+// in the lower layers.
+func (o *Overlay) RemoveAll(name string) error {
+	return o.remove("remove", name, func(top FileSystem) error { return top.RemoveAll(name) })
+}
+
+func (o *Overlay) remove(op, name string, removeTop func(FileSystem) error) error {
+	top, err := o.writable(op, name)
+	if err != nil {
+		return err
+	}
+	lower, err := o.inLower(name)
+	if err != nil {
+		return err
+	}
+	if lower && !o.opts.Whiteouts {
+		return &fs.PathError{Op: op, Path: name, Err: ErrReadOnly}
+	}
+	if err := removeTop(top); err != nil && !(lower && errors.Is(err, fs.ErrNotExist)) {
+		return err
+	}
+	if lower {
+		return o.whiteout(name)
+	}
+	return nil
+}
+
+// Close closes the lower layers and the top layer if it implements
+// io.Closer.
+func (o *Overlay) Close() error {
+	var errs []error
+	if c, ok := o.top.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	for _, l := range o.lower {
+		errs = append(errs, l.Close())
+	}
+	return errors.Join(errs...)
+}
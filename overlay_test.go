@@ -0,0 +1,95 @@
+package fileop_test
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func writeString(t *testing.T, fsys fileop.FileWriterInterface, name, content string) {
+	wt, err := fileop.NewFileWriter(fsys, name, 0, fileop.NONE)
+	require.NoError(t, err)
+	_, err = io.WriteString(wt, content)
+	require.NoError(t, err)
+	require.NoError(t, wt.Close())
+}
+
+func readString(t *testing.T, fsys fileop.Reader, name string) string {
+	rd, err := fsys.Open(name)
+	require.NoError(t, err)
+	defer func() { _ = rd.Close() }()
+	b, err := io.ReadAll(rd)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestOverlay(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	top, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	mid, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	base, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	writeString(t, base, "/d/a.txt", "base a")
+	writeString(t, base, "/d/b.txt", "base b")
+	writeString(t, base, "/e/c.txt", "base c")
+	writeString(t, mid, "/d/a.txt", "mid a")
+	writeString(t, top, "/d/new.txt", "top new")
+
+	o := fileop.NewOverlay(fileop.OverlayOptions{Top: top, Lower: []fileop.ISourceReader{mid, base}, Whiteouts: true})
+	assert.Equal("mid a", readString(t, o, "/d/a.txt"))
+	assert.Equal("base b", readString(t, o, "/d//b.txt"))
+	_, err = o.Open("/d/missing.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+
+	names, err := o.Readdirnames("/d", 0)
+	assert.NoError(err)
+	assert.Equal([]string{"a.txt", "b.txt", "new.txt"}, names)
+
+	// writes go to the top layer
+	writeString(t, o, "/d/a.txt", "top a")
+	assert.Equal("top a", readString(t, o, "/d/a.txt"))
+	assert.Equal("mid a", readString(t, mid, "/d/a.txt"))
+
+	// deletes of lower files leave whiteouts
+	assert.NoError(o.Remove("/d/a.txt"))
+	_, err = o.Stat("/d/a.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+	assert.NoError(o.Rename("/d/b.txt", "/f/b.txt"))
+	assert.Equal("base b", readString(t, o, "/f/b.txt"))
+	names, err = o.Readdirnames("/d", 0)
+	assert.NoError(err)
+	assert.Equal([]string{"new.txt"}, names)
+
+	// a removed directory hides lower entries but not new ones
+	assert.NoError(o.RemoveAll("/e"))
+	_, err = o.Readdir("/e", 0)
+	assert.ErrorIs(err, fs.ErrNotExist)
+	writeString(t, o, "/e/n.txt", "top n")
+	names, err = o.Readdirnames("/e", 0)
+	assert.NoError(err)
+	assert.Equal([]string{"n.txt"}, names)
+
+	var walked []string
+	assert.NoError(o.Walk("/", func(name string, _ fs.FileInfo, err error) error {
+		walked = append(walked, name)
+		return err
+	}))
+	assert.Equal([]string{"/", "/d", "/d/new.txt", "/e", "/e/n.txt", "/f", "/f/b.txt"}, walked)
+
+	// without whiteouts and top layer, lower files can not be changed
+	ro := fileop.NewOverlay(fileop.OverlayOptions{Top: top, Lower: []fileop.ISourceReader{base}})
+	assert.ErrorIs(ro.Remove("/d/b.txt"), fileop.ErrReadOnly)
+	_, err = fileop.NewOverlay(fileop.OverlayOptions{Lower: []fileop.ISourceReader{base}}).Create("/x")
+	assert.ErrorIs(err, fileop.ErrReadOnly)
+	assert.True(strings.HasPrefix(readString(t, ro, "/d/b.txt"), "base"))
+}
@@ -0,0 +1,68 @@
+package fileop
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Path model shared by all integrations:
+//   - Paths are slash-separated and normalized with CleanPath, so "a/b/",
+//     "a//b" and "a/./b" name the same entry.
+//   - Object stores (minio, obs, upyun) have no working directory: "/a/b"
+//     and "a/b" name the same object, see ObjectKey.
+//   - fs.FileInfo.Name returns the base name, without trailing slash for
+//     directories. FileInfo values returned by Readdir and Stat implement
+//     PathInfo to expose the full path; InfoPath falls back to joining.
+
+// PathInfo is implemented by fs.FileInfo values that know their full path.
+type PathInfo interface {
+	Path() string
+}
+
+// CleanPath normalizes name to the slash-separated path model. An empty
+// name becomes ".".
+func CleanPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// ObjectKey converts name to an object store key: cleaned and without
+// leading slash. The bucket root is the empty key.
+func ObjectKey(name string) string {
+	key := strings.TrimPrefix(CleanPath(name), "/")
+	if key == "." {
+		return ""
+	}
+	return key
+}
+
+// DirKey converts dirname to the key prefix listing its entries on an
+// object store: ObjectKey with a trailing slash, or empty for the root.
+func DirKey(dirname string) string {
+	if key := ObjectKey(dirname); key != "" {
+		return key + "/"
+	}
+	return ""
+}
+
+// InfoPath returns the full path of info listed in dir.
+func InfoPath(dir string, info fs.FileInfo) string {
+	if pi, ok := info.(PathInfo); ok {
+		return pi.Path()
+	}
+	return path.Join(CleanPath(dir), info.Name())
+}
+
+// WithPath attaches the full path p to info. It is used by integrations
+// whose underlying FileInfo only knows the base name.
+func WithPath(info fs.FileInfo, p string) fs.FileInfo {
+	return &pathFileInfo{FileInfo: info, path: CleanPath(p)}
+}
+
+type pathFileInfo struct {
+	fs.FileInfo
+	path string
+}
+
+func (f *pathFileInfo) Path() string { return f.path }
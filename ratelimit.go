@@ -0,0 +1,336 @@
+package fileop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrBadRateLimit is returned by WithRateLimit for limiters with a finite
+// limit and a burst of 0, which never allow an event.
+var ErrBadRateLimit = errors.New("rate limiter never allows an event")
+
+// RateLimits configures WithRateLimit. Nil limiters do not limit. Pass the
+// same limiter to several wrapped backends to enforce a process wide limit,
+// e.g. the total upload bandwidth of all targets.
+type RateLimits struct {
+	Read  *rate.Limiter // bytes per second read from streams returned by Open
+	Write *rate.Limiter // bytes per second written through Create and uploads
+	Ops   *rate.Limiter // operations per second, every call counts as one
+}
+
+// WithRateLimit returns fsys with reads and writes throttled to the byte
+// rates and calls throttled to the operation rate of limits. Put from a
+// local file is charged its size before the upload starts. Limiters with a
+// finite limit need a burst of at least 1; WithRateLimit fails with
+// ErrBadRateLimit otherwise. See WithRetry for the meaning of T.
+func WithRateLimit[T any](fsys T, limits RateLimits) (T, error) {
+	for _, l := range []*rate.Limiter{limits.Read, limits.Write, limits.Ops} {
+		if l != nil && l.Limit() != rate.Inf && l.Burst() < 1 {
+			var zero T
+			return zero, fmt.Errorf("rate limit %v with burst %d: %w", l.Limit(), l.Burst(), ErrBadRateLimit)
+		}
+	}
+	return wrapAs[T](newRateLimitedFS(fsys, limits))
+}
+
+type rateLimitedFS struct {
+	forwarder
+	limits RateLimits
+}
+
+func newRateLimitedFS(fsys any, limits RateLimits) *rateLimitedFS {
+	return &rateLimitedFS{
+		forwarder: forwarder{
+			fsys: fsys,
+			rewrap: func(fsys any) any {
+				return newRateLimitedFS(fsys, limits)
+			},
+		},
+		limits: limits,
+	}
+}
+
+// waitN waits until l allows n events, in portions of at most the burst.
+func waitN(l *rate.Limiter, n int) error {
+	if l == nil || l.Limit() == rate.Inf {
+		return nil
+	}
+	for n > 0 {
+		burst := l.Burst()
+		if burst < 1 {
+			return fmt.Errorf("rate limit %v with burst %d: %w", l.Limit(), burst, ErrBadRateLimit)
+		}
+		chunk := min(n, burst)
+		if err := l.WaitN(context.Background(), chunk); err != nil {
+			return fmt.Errorf("rate limit: %w", err)
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (r *rateLimitedFS) op() error {
+	return waitN(r.limits.Ops, 1)
+}
+
+func (r *rateLimitedFS) Open(name string) (io.ReadCloser, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	rd, err := r.forwarder.Open(name)
+	if err != nil || r.limits.Read == nil {
+		return rd, err
+	}
+	return &limitedReader{ReadCloser: rd, limiter: r.limits.Read}, nil
+}
+
+func (r *rateLimitedFS) Create(name string) (io.WriteCloser, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	wt, err := r.forwarder.Create(name)
+	if err != nil || r.limits.Write == nil {
+		return wt, err
+	}
+	return &limitedWriter{WriteCloser: wt, limiter: r.limits.Write}, nil
+}
+
+func (r *rateLimitedFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	return r.forwarder.Readdir(dirname, n)
+}
+
+func (r *rateLimitedFS) Readdirnames(dirname string, n int) ([]string, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	return r.forwarder.Readdirnames(dirname, n)
+}
+
+func (r *rateLimitedFS) Stat(name string) (fs.FileInfo, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	return r.forwarder.Stat(name)
+}
+
+func (r *rateLimitedFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.Walk(root, walkFn)
+}
+
+func (r *rateLimitedFS) Mkdir(dirname string, perm fs.FileMode) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.Mkdir(dirname, perm)
+}
+
+func (r *rateLimitedFS) MkdirAll(dirname string, perm fs.FileMode) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.MkdirAll(dirname, perm)
+}
+
+func (r *rateLimitedFS) Rename(oldPath, newPath string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.Rename(oldPath, newPath)
+}
+
+func (r *rateLimitedFS) Remove(name string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.Remove(name)
+}
+
+func (r *rateLimitedFS) RemoveAll(name string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.RemoveAll(name)
+}
+
+func (r *rateLimitedFS) RemoveBatch(remotes []string) []error {
+	if err := r.op(); err != nil {
+		errs := make([]error, len(remotes))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	return r.forwarder.RemoveBatch(remotes)
+}
+
+func (r *rateLimitedFS) Put(local, remote string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	if r.limits.Write != nil {
+		if info, err := os.Stat(local); err == nil {
+			if err := waitN(r.limits.Write, int(info.Size())); err != nil {
+				return err
+			}
+		}
+	}
+	return r.forwarder.Put(local, remote)
+}
+
+func (r *rateLimitedFS) PutStream(reader io.Reader, remote string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.PutStream(r.limitUpload(reader), remote)
+}
+
+func (r *rateLimitedFS) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.PutStreamWithOptions(r.limitUpload(reader), remote, opts)
+}
+
+func (r *rateLimitedFS) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.PutStreamWithContentType(r.limitUpload(reader), remote, contentType)
+}
+
+func (r *rateLimitedFS) PutEmpty(remote string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.PutEmpty(remote)
+}
+
+func (r *rateLimitedFS) Exist(remote string) bool {
+	if err := r.op(); err != nil {
+		return false
+	}
+	return r.forwarder.Exist(remote)
+}
+
+func (r *rateLimitedFS) ExistE(remote string) (bool, error) {
+	if err := r.op(); err != nil {
+		return false, err
+	}
+	return r.forwarder.ExistE(remote)
+}
+
+func (r *rateLimitedFS) Copy(src, dst string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.Copy(src, dst)
+}
+
+func (r *rateLimitedFS) Move(src, dst string) error {
+	if err := r.op(); err != nil {
+		return err
+	}
+	return r.forwarder.Move(src, dst)
+}
+
+func (r *rateLimitedFS) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	return r.forwarder.ReaddirPrefix(dirname, prefix, n)
+}
+
+func (r *rateLimitedFS) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	if err := r.op(); err != nil {
+		return nil, err
+	}
+	return r.forwarder.ReaddirnamesPrefix(dirname, prefix, n)
+}
+
+func (r *rateLimitedFS) PresignGet(name string, ttl time.Duration) (string, error) {
+	if err := r.op(); err != nil {
+		return "", err
+	}
+	return r.forwarder.PresignGet(name, ttl)
+}
+
+func (r *rateLimitedFS) PresignPut(name string, ttl time.Duration) (string, error) {
+	if err := r.op(); err != nil {
+		return "", err
+	}
+	return r.forwarder.PresignPut(name, ttl)
+}
+
+// limitUpload throttles the bytes the backend reads from reader, keeping
+// io.Seeker for replaying middlewares below.
+func (r *rateLimitedFS) limitUpload(reader io.Reader) io.Reader {
+	if r.limits.Write == nil {
+		return reader
+	}
+	lr := &limitedReader{ReadCloser: io.NopCloser(reader), limiter: r.limits.Write}
+	if s, ok := reader.(io.Seeker); ok {
+		return struct {
+			io.Reader
+			io.Seeker
+		}{lr, s}
+	}
+	return lr
+}
+
+// limitedReader reads at most the burst at once and waits for the bytes it
+// returns.
+type limitedReader struct {
+	io.ReadCloser
+	limiter *rate.Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); burst > 0 && len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if err := waitN(r.limiter, n); err != nil {
+		return n, err
+	}
+	return n, err
+}
+
+// limitedWriter waits before writing every portion of the burst size.
+type limitedWriter struct {
+	io.WriteCloser
+	limiter *rate.Limiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if burst := w.limiter.Burst(); burst > 0 && len(chunk) > burst {
+			chunk = chunk[:burst]
+		}
+		if err := waitN(w.limiter, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.WriteCloser.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
@@ -0,0 +1,69 @@
+package fileop_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	wt, err := fileop.NewFileWriter(mfs, "/a.txt", 0, fileop.NONE)
+	assert.NoError(err)
+	_, err = wt.Write([]byte(strings.Repeat("x", 3000)))
+	assert.NoError(err)
+	assert.NoError(wt.Close())
+
+	// the limiter is shared by both wrappers, 10000 bytes per second with
+	// the first 1000 bytes free, so reading twice takes 500ms; the bounds
+	// below leave half of it as margin for coarse clocks
+	read := rate.NewLimiter(10000, 1000)
+	a, err := fileop.WithRateLimit[fileop.FileSystem](mfs, fileop.RateLimits{Read: read})
+	assert.NoError(err)
+	b, err := fileop.WithRateLimit[fileop.FileSystem](mfs, fileop.RateLimits{Read: read})
+	assert.NoError(err)
+
+	start := time.Now()
+	for _, fsys := range []fileop.FileSystem{a, b} {
+		rd, err := fsys.Open("/a.txt")
+		assert.NoError(err)
+		n, err := io.Copy(io.Discard, rd)
+		assert.NoError(err)
+		assert.EqualValues(3000, n)
+		assert.NoError(rd.Close())
+	}
+	assert.GreaterOrEqual(time.Since(start), 250*time.Millisecond)
+
+	ops, err := fileop.WithRateLimit[fileop.FileSystem](mfs, fileop.RateLimits{Ops: rate.NewLimiter(20, 1)})
+	assert.NoError(err)
+	start = time.Now()
+	for range 5 {
+		_, err := ops.Stat("/a.txt")
+		assert.NoError(err)
+	}
+	// 200ms for the 4 calls after the first
+	assert.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+
+	// a finite limit with burst 0 never allows an event
+	_, err = fileop.WithRateLimit[fileop.FileSystem](mfs, fileop.RateLimits{Write: rate.NewLimiter(10, 0)})
+	assert.ErrorIs(err, fileop.ErrBadRateLimit)
+	_, err = fileop.WithRateLimit[fileop.FileSystem](mfs, fileop.RateLimits{Write: rate.NewLimiter(rate.Inf, 0)})
+	assert.NoError(err)
+	limiter := rate.NewLimiter(10, 1)
+	ops, err = fileop.WithRateLimit[fileop.FileSystem](mfs, fileop.RateLimits{Ops: limiter})
+	assert.NoError(err)
+	limiter.SetBurst(0)
+	_, err = ops.Stat("/a.txt")
+	assert.ErrorIs(err, fileop.ErrBadRateLimit)
+}
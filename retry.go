@@ -0,0 +1,263 @@
+package fileop
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrNotReplayable marks the failure of a PutStream that WithRetry could not
+// retry because the reader can not be read a second time.
+var ErrNotReplayable = errors.New("stream not replayable")
+
+// RetryPolicy configures WithRetry. Zero fields take the defaults.
+type RetryPolicy struct {
+	MaxAttempts int              // attempts including the first one, default 3
+	BaseDelay   time.Duration    // backoff before the first retry, default 100ms
+	MaxDelay    time.Duration    // backoff limit, default 10s
+	Retryable   func(error) bool // default IsRetryable
+
+	// StreamBuffer is the number of bytes of a PutStream reader kept in
+	// memory so that the upload can be retried. Readers implementing
+	// io.Seeker are rewound instead. Longer streams, and every stream when
+	// StreamBuffer is 0, are uploaded once and a retryable failure is
+	// returned classified as ErrNotReplayable.
+	StreamBuffer int64
+}
+
+// WithRetry returns fsys with idempotent operations retried on retryable
+// errors, waiting with exponential backoff and jitter in between: Open,
+// Stat, Readdir, Readdirnames, their prefix variants, Copy, PresignGet,
+// PresignPut, Put, PutEmpty, Exist and ExistE, as well as
+// PutStream and its variants when the reader can be replayed. Errors while
+// reading an opened file are not retried. The last error is returned when
+// all attempts fail.
+//
+// T is the interface type of fsys, such as FileSystem, ISourceReader,
+// ITargetUploader or FileSystemSimpleBucket; WithRetry fails with
+// ErrUnsupported for concrete types. Optional interfaces such as Copier are
+// forwarded, and detected with As.
+func WithRetry[T any](fsys T, policy RetryPolicy) (T, error) {
+	return wrapAs[T](newRetryFS(fsys, policy))
+}
+
+type retryFS struct {
+	forwarder
+	policy RetryPolicy
+}
+
+func newRetryFS(fsys any, policy RetryPolicy) *retryFS {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 3
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = 100 * time.Millisecond
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = 10 * time.Second
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+	r := &retryFS{policy: policy}
+	r.forwarder = forwarder{
+		fsys: fsys,
+		rewrap: func(fsys any) any {
+			return newRetryFS(fsys, r.policy)
+		},
+	}
+	return r
+}
+
+// do calls fn until it succeeds, fails with an error that is not retryable
+// or the attempts are exhausted.
+func (r *retryFS) do(fn func() error) error {
+	var err error
+	for attempt := 0; attempt < r.policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(r.backoff(attempt))
+		}
+		if err = fn(); err == nil || !r.policy.Retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff returns the delay before the given retry: the exponential delay
+// capped at MaxDelay, of which the second half is random.
+func (r *retryFS) backoff(attempt int) time.Duration {
+	delay := r.policy.MaxDelay
+	if attempt < 32 {
+		delay = min(r.policy.BaseDelay<<(attempt-1), r.policy.MaxDelay)
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (r *retryFS) Open(name string) (io.ReadCloser, error) {
+	var rd io.ReadCloser
+	err := r.do(func() (err error) {
+		rd, err = r.forwarder.Open(name)
+		return err
+	})
+	return rd, err
+}
+
+func (r *retryFS) Stat(name string) (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := r.do(func() (err error) {
+		info, err = r.forwarder.Stat(name)
+		return err
+	})
+	return info, err
+}
+
+func (r *retryFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	err := r.do(func() (err error) {
+		infos, err = r.forwarder.Readdir(dirname, n)
+		return err
+	})
+	return infos, err
+}
+
+func (r *retryFS) Readdirnames(dirname string, n int) ([]string, error) {
+	var names []string
+	err := r.do(func() (err error) {
+		names, err = r.forwarder.Readdirnames(dirname, n)
+		return err
+	})
+	return names, err
+}
+
+func (r *retryFS) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	err := r.do(func() (err error) {
+		infos, err = r.forwarder.ReaddirPrefix(dirname, prefix, n)
+		return err
+	})
+	return infos, err
+}
+
+func (r *retryFS) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	var names []string
+	err := r.do(func() (err error) {
+		names, err = r.forwarder.ReaddirnamesPrefix(dirname, prefix, n)
+		return err
+	})
+	return names, err
+}
+
+func (r *retryFS) Copy(src, dst string) error {
+	return r.do(func() error {
+		return r.forwarder.Copy(src, dst)
+	})
+}
+
+func (r *retryFS) PresignGet(name string, ttl time.Duration) (string, error) {
+	var url string
+	err := r.do(func() (err error) {
+		url, err = r.forwarder.PresignGet(name, ttl)
+		return err
+	})
+	return url, err
+}
+
+func (r *retryFS) PresignPut(name string, ttl time.Duration) (string, error) {
+	var url string
+	err := r.do(func() (err error) {
+		url, err = r.forwarder.PresignPut(name, ttl)
+		return err
+	})
+	return url, err
+}
+
+func (r *retryFS) Put(local, remote string) error {
+	return r.do(func() error {
+		return r.forwarder.Put(local, remote)
+	})
+}
+
+func (r *retryFS) PutEmpty(remote string) error {
+	return r.do(func() error {
+		return r.forwarder.PutEmpty(remote)
+	})
+}
+
+func (r *retryFS) ExistE(remote string) (bool, error) {
+	var exist bool
+	err := r.do(func() (err error) {
+		exist, err = r.forwarder.ExistE(remote)
+		return err
+	})
+	return exist, err
+}
+
+// Exist retries through ExistE when fsys implements it.
+func (r *retryFS) Exist(remote string) bool {
+	if _, ok := As[interface {
+		ExistE(remote string) (bool, error)
+	}](r.fsys); !ok {
+		return r.forwarder.Exist(remote)
+	}
+	exist, _ := r.ExistE(remote)
+	return exist
+}
+
+func (r *retryFS) PutStream(reader io.Reader, remote string) error {
+	return r.putStream(reader, func(rd io.Reader) error {
+		return r.forwarder.PutStream(rd, remote)
+	})
+}
+
+func (r *retryFS) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	return r.putStream(reader, func(rd io.Reader) error {
+		return r.forwarder.PutStreamWithOptions(rd, remote, opts)
+	})
+}
+
+func (r *retryFS) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	return r.putStream(reader, func(rd io.Reader) error {
+		return r.forwarder.PutStreamWithContentType(rd, remote, contentType)
+	})
+}
+
+// putStream retries put with reader rewound, or buffered up to
+// StreamBuffer bytes.
+func (r *retryFS) putStream(reader io.Reader, put func(rd io.Reader) error) error {
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			return r.do(func() error {
+				if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+					return fmt.Errorf("rewind stream: %w", err)
+				}
+				return put(seeker)
+			})
+		}
+	}
+
+	if r.policy.StreamBuffer > 0 {
+		buf, err := io.ReadAll(io.LimitReader(reader, r.policy.StreamBuffer+1))
+		if err != nil {
+			return fmt.Errorf("buffer stream: %w", err)
+		}
+		if int64(len(buf)) <= r.policy.StreamBuffer {
+			return r.do(func() error {
+				return put(bytes.NewReader(buf))
+			})
+		}
+		reader = io.MultiReader(bytes.NewReader(buf), reader)
+	}
+
+	err := put(reader)
+	if err != nil && r.policy.Retryable(err) {
+		return Classify(err, ErrNotReplayable)
+	}
+	return err
+}
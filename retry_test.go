@@ -0,0 +1,152 @@
+package fileop_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+// flakyFS fails the first failures calls of every operation with err.
+type flakyFS struct {
+	*afero.Handler
+	failures int
+	err      error
+
+	mu    sync.Mutex
+	calls map[string]int
+	data  map[string]string
+}
+
+func (f *flakyFS) fail(op string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[op]++
+	if f.calls[op] <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyFS) Open(name string) (io.ReadCloser, error) {
+	if err := f.fail("open"); err != nil {
+		return nil, err
+	}
+	return f.Handler.Open(name)
+}
+
+func (f *flakyFS) PutStream(reader io.Reader, remote string) error {
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := f.fail("put"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[remote] = string(b)
+	return nil
+}
+
+func newFlakyFS(t *testing.T, failures int, err error) *flakyFS {
+	mfs, e := afero.New(afero.Memory)
+	require.NoError(t, e)
+	return &flakyFS{Handler: mfs, failures: failures, err: err, calls: map[string]int{}, data: map[string]string{}}
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	policy := fileop.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	flaky := newFlakyFS(t, 2, fileop.ErrUnavailable)
+	wt, err := fileop.NewFileWriter(flaky, "/a.txt", 0, fileop.NONE)
+	assert.NoError(err)
+	assert.NoError(wt.Close())
+
+	fsys, err := fileop.WithRetry[fileop.FileSystem](flaky, policy)
+	assert.NoError(err)
+	rd, err := fsys.Open("/a.txt")
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+	assert.Equal(3, flaky.calls["open"])
+
+	flaky = newFlakyFS(t, 3, fileop.ErrUnavailable)
+	fsys, err = fileop.WithRetry[fileop.FileSystem](flaky, policy)
+	assert.NoError(err)
+	_, err = fsys.Open("/a.txt")
+	assert.ErrorIs(err, fileop.ErrUnavailable)
+	assert.Equal(3, flaky.calls["open"])
+
+	flaky = newFlakyFS(t, 1, fs.ErrNotExist)
+	fsys, err = fileop.WithRetry[fileop.FileSystem](flaky, policy)
+	assert.NoError(err)
+	_, err = fsys.Open("/a.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+	assert.Equal(1, flaky.calls["open"])
+}
+
+func TestWithRetryPutStream(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	policy := fileop.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	type uploader interface {
+		PutStream(reader io.Reader, remote string) error
+	}
+	putStream := func(flaky *flakyFS, reader io.Reader, remote string) error {
+		u, err := fileop.WithRetry[uploader](flaky, policy)
+		assert.NoError(err)
+		return u.PutStream(reader, remote)
+	}
+
+	// seekable readers are rewound
+	flaky := newFlakyFS(t, 1, fileop.ErrUnavailable)
+	assert.NoError(putStream(flaky, strings.NewReader("seek"), "/a"))
+	assert.Equal("seek", flaky.data["/a"])
+
+	// other readers are rejected without buffer
+	flaky = newFlakyFS(t, 1, fileop.ErrUnavailable)
+	err := putStream(flaky, io.MultiReader(strings.NewReader("once")), "/b")
+	assert.ErrorIs(err, fileop.ErrNotReplayable)
+	assert.ErrorIs(err, fileop.ErrUnavailable)
+	assert.Equal(1, flaky.calls["put"])
+
+	// and buffered when short enough
+	policy.StreamBuffer = 16
+	flaky = newFlakyFS(t, 1, fileop.ErrUnavailable)
+	assert.NoError(putStream(flaky, io.MultiReader(strings.NewReader("buffered")), "/c"))
+	assert.Equal("buffered", flaky.data["/c"])
+
+	flaky = newFlakyFS(t, 1, fileop.ErrUnavailable)
+	long := strings.Repeat("x", 17)
+	err = putStream(flaky, io.MultiReader(strings.NewReader(long)), "/d")
+	assert.True(errors.Is(err, fileop.ErrNotReplayable))
+
+	flaky.failures = 0
+	assert.NoError(putStream(flaky, io.MultiReader(strings.NewReader(long)), "/d"))
+	assert.Equal(long, flaky.data["/d"])
+}
+
+func TestWithRetryBackoff(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	policy := fileop.RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second, 100: time.Second} {
+		for range 10 {
+			d := fileop.Backoff(policy, attempt)
+			assert.GreaterOrEqual(d, want/2, "attempt %d", attempt)
+			assert.LessOrEqual(d, want, "attempt %d", attempt)
+		}
+	}
+}
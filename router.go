@@ -0,0 +1,321 @@
+package fileop
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Mount attaches a file system to a path of a Router. The file system sees
+// the paths below the mount point, rooted at "/".
+type Mount struct {
+	Path string // mount point, e.g. "/raw"
+	FS   FileSystem
+}
+
+// Router is a file system dispatching paths to the mount with the longest
+// matching mount point. Listings of directories containing mount points
+// show them as directories. Renames and copies across mounts stream the
+// content from one mount to the other.
+type Router struct {
+	mounts []Mount // longest mount point first
+}
+
+var (
+	_ FileSystemWithCloser = (*Router)(nil)
+	_ Copier               = (*Router)(nil)
+)
+
+// NewRouter creates a router of mounts. Mount points must be unique; paths
+// not below any mount point do not exist unless "/" is mounted.
+func NewRouter(mounts ...Mount) (*Router, error) {
+	r := &Router{}
+	seen := make(map[string]bool)
+	for _, m := range mounts {
+		if m.FS == nil {
+			return nil, fmt.Errorf("mount %s: no file system", m.Path)
+		}
+		m.Path = CleanPath("/" + m.Path)
+		if seen[m.Path] {
+			return nil, fmt.Errorf("mount %s: duplicate mount point", m.Path)
+		}
+		seen[m.Path] = true
+		r.mounts = append(r.mounts, m)
+	}
+	sort.SliceStable(r.mounts, func(i, j int) bool { return len(r.mounts[i].Path) > len(r.mounts[j].Path) })
+	return r, nil
+}
+
+// resolve returns the mount of name and the path within it.
+func (r *Router) resolve(op, name string) (Mount, string, error) {
+	full := CleanPath("/" + name)
+	for _, m := range r.mounts {
+		if m.Path == "/" {
+			return m, full, nil
+		}
+		if full == m.Path {
+			return m, "/", nil
+		}
+		if strings.HasPrefix(full, m.Path+"/") {
+			return m, strings.TrimPrefix(full, m.Path), nil
+		}
+	}
+	return Mount{}, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// mountPoints returns the names of the directories below dir leading to
+// mount points, without those equal to dir.
+func (r *Router) mountPoints(dir string) []string {
+	dir = CleanPath("/" + dir)
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range r.mounts {
+		if !isAncestor(dir, m.Path) {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(m.Path, dir), "/")
+		name, _, _ := strings.Cut(rest, "/")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isMountDir reports whether name is a mount point or contains one.
+func (r *Router) isMountDir(name string) bool {
+	name = CleanPath("/" + name)
+	for _, m := range r.mounts {
+		if m.Path == name || isAncestor(name, m.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// outer converts info of inner on m to the router's paths.
+func outer(m Mount, inner string, info fs.FileInfo) fs.FileInfo {
+	return WithPath(info, path.Join(m.Path, inner))
+}
+
+func (r *Router) Open(name string) (io.ReadCloser, error) {
+	m, inner, err := r.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return m.FS.Open(inner)
+}
+
+// Stat reports mount points and their ancestors as directories.
+func (r *Router) Stat(name string) (fs.FileInfo, error) {
+	if r.isMountDir(name) {
+		return mountInfo(CleanPath("/" + name)), nil
+	}
+	m, inner, err := r.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := m.FS.Stat(inner)
+	if err != nil {
+		return nil, err
+	}
+	return outer(m, inner, info), nil
+}
+
+// Readdir lists dirname on its mount, adding the mount points below it.
+// Mount points shadow entries of the same name. Entries are sorted by name,
+// so n > 0 returns the first n of them.
+func (r *Router) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	dir := CleanPath("/" + dirname)
+	points := r.mountPoints(dir)
+	var infos []fs.FileInfo
+	m, inner, err := r.resolve("readdir", dirname)
+	if err == nil {
+		infos, err = m.FS.Readdir(inner, 0)
+		if err != nil && !(len(points) > 0 && errors.Is(err, fs.ErrNotExist)) {
+			return nil, err
+		}
+	} else if len(points) == 0 {
+		return nil, err
+	}
+
+	shadowed := make(map[string]bool, len(points))
+	for _, p := range points {
+		shadowed[p] = true
+	}
+	result := make([]fs.FileInfo, 0, len(infos)+len(points))
+	for _, info := range infos {
+		if !shadowed[info.Name()] {
+			result = append(result, outer(m, InfoPath(inner, info), info))
+		}
+	}
+	for _, p := range points {
+		result = append(result, mountInfo(path.Join(dir, p)))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result, nil
+}
+
+func (r *Router) Readdirnames(dirname string, n int) ([]string, error) {
+	infos, err := r.Readdir(dirname, n)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, nil
+}
+
+// Walk walks the tree across mount points like filepath.Walk.
+func (r *Router) Walk(root string, walkFn filepath.WalkFunc) error {
+	return Walk(r, root, walkFn)
+}
+
+func (r *Router) Mkdir(dirname string, perm fs.FileMode) error {
+	if r.isMountDir(dirname) {
+		return &fs.PathError{Op: "mkdir", Path: dirname, Err: fs.ErrExist}
+	}
+	m, inner, err := r.resolve("mkdir", dirname)
+	if err != nil {
+		return err
+	}
+	return m.FS.Mkdir(inner, perm)
+}
+
+// MkdirAll succeeds for mount points and their ancestors.
+func (r *Router) MkdirAll(dirname string, perm fs.FileMode) error {
+	if r.isMountDir(dirname) {
+		return nil
+	}
+	m, inner, err := r.resolve("mkdir", dirname)
+	if err != nil {
+		return err
+	}
+	return m.FS.MkdirAll(inner, perm)
+}
+
+func (r *Router) Create(name string) (io.WriteCloser, error) {
+	m, inner, err := r.resolve("create", name)
+	if err != nil {
+		return nil, err
+	}
+	return m.FS.Create(inner)
+}
+
+// Remove fails with fs.ErrPermission for mount points and their ancestors.
+func (r *Router) Remove(name string) error {
+	m, inner, err := r.resolveWrite("remove", name)
+	if err != nil {
+		return err
+	}
+	return m.FS.Remove(inner)
+}
+
+// RemoveAll fails with fs.ErrPermission for mount points and their
+// ancestors.
+func (r *Router) RemoveAll(name string) error {
+	m, inner, err := r.resolveWrite("remove", name)
+	if err != nil {
+		return err
+	}
+	return m.FS.RemoveAll(inner)
+}
+
+// resolveWrite resolves name for changing it, which is not allowed for
+// mount points and their ancestors.
+func (r *Router) resolveWrite(op, name string) (Mount, string, error) {
+	if r.isMountDir(name) {
+		return Mount{}, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return r.resolve(op, name)
+}
+
+// Rename renames within a mount, and copies files across mounts before
+// removing the source. Directories can not be renamed across mounts.
+func (r *Router) Rename(oldPath, newPath string) error {
+	return r.Move(oldPath, newPath)
+}
+
+// Copy copies src to dst with Copy of the mount when both are on the same
+// mount, so that a Copier found through middlewares copies server side,
+// and streams the content otherwise.
+func (r *Router) Copy(src, dst string) error {
+	sm, sin, err := r.resolve("copy", src)
+	if err != nil {
+		return err
+	}
+	dm, din, err := r.resolveWrite("copy", dst)
+	if err != nil {
+		return err
+	}
+	if sm.Path == dm.Path {
+		return Copy(sm.FS, sin, din)
+	}
+	return copyStream(sm.FS, sin, dm.FS, din)
+}
+
+// Move is Rename; see there. Within a mount it uses Move of the mount.
+func (r *Router) Move(src, dst string) error {
+	sm, sin, err := r.resolveWrite("rename", src)
+	if err != nil {
+		return err
+	}
+	dm, din, err := r.resolveWrite("rename", dst)
+	if err != nil {
+		return err
+	}
+	if sm.Path == dm.Path {
+		return Move(sm.FS, sin, din)
+	}
+	info, err := sm.FS.Stat(sin)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "rename", Path: src, Err: ErrUnsupported}
+	}
+	if err := copyStream(sm.FS, sin, dm.FS, din); err != nil {
+		return err
+	}
+	if err := sm.FS.Remove(sin); err != nil {
+		return fmt.Errorf("remove %s: %w", src, err)
+	}
+	return nil
+}
+
+// Close closes the mounted file systems implementing io.Closer.
+func (r *Router) Close() error {
+	var errs []error
+	for _, m := range r.mounts {
+		if c, ok := m.FS.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mountInfo describes a mount point or one of its ancestors.
+func mountInfo(p string) fs.FileInfo {
+	return &pathFileInfo{FileInfo: mountDirInfo(path.Base(p)), path: p}
+}
+
+type mountDirInfo string
+
+func (d mountDirInfo) Name() string       { return string(d) }
+func (d mountDirInfo) Size() int64        { return 0 }
+func (d mountDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0755 }
+func (d mountDirInfo) ModTime() time.Time { return time.Time{} }
+func (d mountDirInfo) IsDir() bool        { return true }
+func (d mountDirInfo) Sys() any           { return nil }
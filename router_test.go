@@ -0,0 +1,90 @@
+package fileop_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	rootFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	rawFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	archiveFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	writeString(t, rootFS, "/readme.txt", "root")
+	writeString(t, rawFS, "/2021/a.txt", "raw a")
+
+	r, err := fileop.NewRouter(
+		fileop.Mount{Path: "/", FS: rootFS},
+		fileop.Mount{Path: "/data/raw/", FS: rawFS},
+		fileop.Mount{Path: "archive", FS: archiveFS},
+	)
+	assert.NoError(err)
+	_, err = fileop.NewRouter(fileop.Mount{Path: "/a", FS: rootFS}, fileop.Mount{Path: "/a/", FS: rawFS})
+	assert.Error(err)
+
+	assert.Equal("raw a", readString(t, r, "/data/raw/2021/a.txt"))
+	assert.Equal("root", readString(t, r, "/readme.txt"))
+
+	// mount points appear in listings of their ancestors
+	names, err := r.Readdirnames("/", 0)
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"readme.txt", "data", "archive"}, names)
+	names, err = r.Readdirnames("/", 2)
+	assert.NoError(err)
+	assert.Equal([]string{"archive", "data"}, names)
+	infos, err := r.Readdir("/data/raw/2021", 0)
+	assert.NoError(err)
+	assert.Len(infos, 1)
+	assert.Equal("/data/raw/2021/a.txt", fileop.InfoPath("/data/raw/2021", infos[0]))
+	info, err := r.Stat("/data")
+	assert.NoError(err)
+	assert.True(info.IsDir())
+
+	// writes go to the mount, renames across mounts copy
+	writeString(t, r, "/archive/x/b.txt", "b")
+	assert.Equal("b", readString(t, archiveFS, "/x/b.txt"))
+	assert.NoError(r.Rename("/data/raw/2021/a.txt", "/archive/2021/a.txt"))
+	assert.Equal("raw a", readString(t, archiveFS, "/2021/a.txt"))
+	_, err = rawFS.Stat("/2021/a.txt")
+	assert.ErrorIs(err, fs.ErrNotExist)
+	assert.NoError(fileop.Copy(r, "/archive/2021/a.txt", "/copy.txt"))
+	assert.Equal("raw a", readString(t, rootFS, "/copy.txt"))
+	assert.NoError(r.Rename("/archive/x/b.txt", "/archive/y/b.txt"))
+
+	assert.ErrorIs(r.RemoveAll("/data"), fs.ErrPermission)
+
+	var walked []string
+	assert.NoError(r.Walk("/data", func(name string, _ fs.FileInfo, err error) error {
+		walked = append(walked, name)
+		return err
+	}))
+	assert.Equal([]string{"/data", "/data/raw", "/data/raw/2021"}, walked)
+}
+
+func TestRouterCopier(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	cfs := newCopierFS(t)
+	wrapped, err := fileop.WithRetry[fileop.FileSystem](cfs, fileop.RetryPolicy{})
+	assert.NoError(err)
+	r, err := fileop.NewRouter(fileop.Mount{Path: "/objects", FS: wrapped})
+	assert.NoError(err)
+
+	writeString(t, r, "/objects/a.txt", "a")
+	assert.NoError(r.Copy("/objects/a.txt", "/objects/b.txt"))
+	assert.NoError(r.Move("/objects/b.txt", "/objects/c.txt"))
+	assert.Equal(1, cfs.Calls("copy"))
+	assert.Equal(1, cfs.Calls("move"))
+	assert.Equal("a", readString(t, r, "/objects/c.txt"))
+}
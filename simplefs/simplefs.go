@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"path/filepath"
 
 	"github.com/marsgopher/fileop"
 	"github.com/marsgopher/fileop/filetarget"
@@ -91,6 +90,8 @@ func New(c Config) (fileop.FileSystemSimpleBucket, error) {
 	}
 }
 
+// WrapFS adapts a FileSystemWithCloser to FileSystemSimpleBucket. Paths are
+// resolved below BasePath with fileop.SubPath, so ".." cannot leave it.
 type WrapFS struct {
 	Source   fileop.FileSystemWithCloser
 	Target   *filetarget.WrapFS
@@ -103,46 +104,148 @@ func (w *WrapFS) Bucket(name string) fileop.FileSystemSimpleBucket {
 	return &cp
 }
 
+func (w *WrapFS) join(name string) (string, error) {
+	if w.BasePath == "" {
+		return fileop.CleanPath(name), nil
+	}
+	return fileop.SubPath(w.BasePath, name)
+}
+
+// source returns Source restricted to BasePath, so that the paths of listed
+// FileInfo values are relative to it.
+func (w *WrapFS) source() (fileop.DirReader, error) {
+	if w.BasePath == "" {
+		return w.Source, nil
+	}
+	return fileop.Sub[fileop.DirReader](w.Source, w.BasePath)
+}
+
 func (w *WrapFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
-	dirname = filepath.Join(w.BasePath, dirname)
-	return w.Source.Readdir(dirname, n)
+	src, err := w.source()
+	if err != nil {
+		return nil, err
+	}
+	return src.Readdir(fileop.CleanPath(dirname), n)
 }
 
 func (w *WrapFS) Readdirnames(dirname string, n int) ([]string, error) {
-	dirname = filepath.Join(w.BasePath, dirname)
-	return w.Source.Readdirnames(dirname, n)
+	src, err := w.source()
+	if err != nil {
+		return nil, err
+	}
+	return src.Readdirnames(fileop.CleanPath(dirname), n)
 }
 
 func (w *WrapFS) Open(name string) (io.ReadCloser, error) {
-	name = filepath.Join(w.BasePath, name)
+	name, err := w.join(name)
+	if err != nil {
+		return nil, err
+	}
 	return w.Source.Open(name)
 }
 
 func (w *WrapFS) Put(local, remote string) error {
-	remote = filepath.Join(w.BasePath, remote)
+	remote, err := w.join(remote)
+	if err != nil {
+		return err
+	}
 	return w.Target.Put(local, remote)
 }
 
 func (w *WrapFS) PutStream(reader io.Reader, remote string) error {
-	remote = filepath.Join(w.BasePath, remote)
+	remote, err := w.join(remote)
+	if err != nil {
+		return err
+	}
 	return w.Target.PutStream(reader, remote)
 }
 
 func (w *WrapFS) PutStreamWithContentType(reader io.Reader, remote string, _ string) error {
-	remote = filepath.Join(w.BasePath, remote)
+	remote, err := w.join(remote)
+	if err != nil {
+		return err
+	}
 	return w.Target.PutStream(reader, remote)
 }
 
+func (w *WrapFS) PutStreamWithOptions(reader io.Reader, remote string, opts fileop.PutOptions) error {
+	remote, err := w.join(remote)
+	if err != nil {
+		return err
+	}
+	return w.Target.PutStreamWithOptions(reader, remote, opts)
+}
+
 func (w *WrapFS) PutEmpty(remote string) error {
-	remote = filepath.Join(w.BasePath, remote)
+	remote, err := w.join(remote)
+	if err != nil {
+		return err
+	}
 	return w.Target.PutEmpty(remote)
 }
 
+func (w *WrapFS) Remove(remote string) error {
+	remote, err := w.join(remote)
+	if err != nil {
+		return err
+	}
+	return w.Target.Remove(remote)
+}
+
+func (w *WrapFS) RemoveBatch(remotes []string) []error {
+	errs := make([]error, len(remotes))
+	joined := make([]string, 0, len(remotes))
+	index := make([]int, 0, len(remotes))
+	for i, remote := range remotes {
+		full, err := w.join(remote)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		joined = append(joined, full)
+		index = append(index, i)
+	}
+	failed := len(joined) < len(remotes)
+	for j, err := range w.Target.RemoveBatch(joined) {
+		if err != nil {
+			errs[index[j]] = err
+			failed = true
+		}
+	}
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
 func (w *WrapFS) Exist(remote string) bool {
-	remote = filepath.Join(w.BasePath, remote)
+	remote, err := w.join(remote)
+	if err != nil {
+		return false
+	}
 	return w.Target.Exist(remote)
 }
 
+func (w *WrapFS) ExistE(remote string) (bool, error) {
+	remote, err := w.join(remote)
+	if err != nil {
+		return false, err
+	}
+	return w.Target.ExistE(remote)
+}
+
+// Stat returns the FileInfo of remote with a path relative to BasePath.
+func (w *WrapFS) Stat(remote string) (fs.FileInfo, error) {
+	if w.BasePath == "" {
+		return w.Target.Stat(fileop.CleanPath(remote))
+	}
+	st, err := fileop.Sub[fileop.Stater](w.Target, w.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	return st.Stat(remote)
+}
+
 func (w *WrapFS) Close() error {
 	return w.Source.Close()
 }
@@ -0,0 +1,50 @@
+package simplefs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/filetarget"
+	"github.com/marsgopher/fileop/fileutil"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestWrapFSBasePath(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	for _, name := range []string{"/base/d/a.txt", "/base/d/e/b.txt"} {
+		assert.NoError(fileutil.WriteFile(mfs, name, strings.NewReader(name)))
+	}
+	w := &WrapFS{Source: mfs, Target: &filetarget.WrapFS{Target: mfs}, BasePath: "/base"}
+
+	infos, err := w.Readdir("/d", 0)
+	assert.NoError(err)
+	var paths []string
+	for _, info := range infos {
+		paths = append(paths, fileop.InfoPath("/d", info))
+	}
+	assert.ElementsMatch([]string{"/d/a.txt", "/d/e"}, paths)
+	info, err := w.Stat("d/a.txt")
+	assert.NoError(err)
+	assert.Equal("/d/a.txt", fileop.InfoPath("/d", info))
+
+	dstFS, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	report, err := fileutil.CopyTree(w, &filetarget.WrapFS{Target: dstFS}, fileutil.CopyOptions{
+		SrcDir:  "/d",
+		DstDir:  "/dst",
+		Workers: 2,
+	})
+	assert.NoError(err)
+	assert.Empty(report.Failed)
+	assert.Equal([]string{"/d/a.txt", "/d/e/b.txt"}, report.Copied)
+	content, err := fileutil.ReadFile(dstFS, "/dst/e/b.txt")
+	assert.NoError(err)
+	assert.Equal("/base/d/e/b.txt", string(content))
+}
@@ -0,0 +1,368 @@
+package fileop
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPathEscape is returned for paths leaving the base of Sub, such as
+	// "../etc". It matches fs.ErrPermission.
+	ErrPathEscape = Classify(errors.New("path escapes base"), fs.ErrPermission)
+	// ErrReadOnly is returned for writes to a read-only Sub. It matches
+	// fs.ErrPermission.
+	ErrReadOnly = Classify(errors.New("read-only file system"), fs.ErrPermission)
+)
+
+// SubPath joins name below base. Unlike path.Join it fails with
+// ErrPathEscape when ".." segments of name leave base. Both "/a" and "a"
+// name the same path below base.
+func SubPath(base, name string) (string, error) {
+	depth := 0
+	for _, seg := range strings.Split(filepath.ToSlash(name), "/") {
+		switch seg {
+		case "", ".":
+		case "..":
+			if depth--; depth < 0 {
+				return "", &fs.PathError{Op: "sub", Path: name, Err: ErrPathEscape}
+			}
+		default:
+			depth++
+		}
+	}
+	return path.Join(CleanPath(base), CleanPath("/"+filepath.ToSlash(name))), nil
+}
+
+// SubOptions configures SubWithOptions.
+type SubOptions struct {
+	ReadOnly bool // fail every write with ErrReadOnly
+}
+
+// Sub returns fsys restricted to the tree below base, which appears as the
+// root "/". Paths leaving base fail with ErrPathEscape, and the paths of
+// returned FileInfo values are relative to the new root. Buckets of the
+// returned value are restricted to base within the bucket. See WithRetry for
+// the meaning of T.
+func Sub[T any](fsys T, base string) (T, error) {
+	return SubWithOptions(fsys, base, SubOptions{})
+}
+
+// SubWithOptions is Sub with options, e.g. to make the tree read-only.
+func SubWithOptions[T any](fsys T, base string, opts SubOptions) (T, error) {
+	return wrapAs[T](newSubFS(fsys, base, opts))
+}
+
+type subFS struct {
+	forwarder
+	base string
+	opts SubOptions
+}
+
+func newSubFS(fsys any, base string, opts SubOptions) *subFS {
+	return &subFS{
+		forwarder: forwarder{
+			fsys: fsys,
+			rewrap: func(fsys any) any {
+				return newSubFS(fsys, base, opts)
+			},
+		},
+		base: CleanPath(base),
+		opts: opts,
+	}
+}
+
+func (s *subFS) join(name string) (string, error) {
+	return SubPath(s.base, name)
+}
+
+// write resolves name for a write operation.
+func (s *subFS) write(op, name string) (string, error) {
+	if s.opts.ReadOnly {
+		return "", &fs.PathError{Op: op, Path: name, Err: ErrReadOnly}
+	}
+	return s.join(name)
+}
+
+// rel converts a full path below base to the path below the new root.
+func (s *subFS) rel(full string) string {
+	full = CleanPath(full)
+	switch {
+	case full == s.base:
+		return "/"
+	case s.base == "/":
+		return full
+	case s.base == ".":
+		if !strings.HasPrefix(full, "/") {
+			return "/" + full
+		}
+	case strings.HasPrefix(full, s.base+"/"):
+		return strings.TrimPrefix(full, s.base)
+	}
+	return full
+}
+
+func (s *subFS) relInfo(dir string, info fs.FileInfo) fs.FileInfo {
+	return WithPath(info, s.rel(InfoPath(dir, info)))
+}
+
+func (s *subFS) Open(name string) (io.ReadCloser, error) {
+	full, err := s.join(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.forwarder.Open(full)
+}
+
+func (s *subFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	full, err := s.join(dirname)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := s.forwarder.Readdir(full, n)
+	for i, info := range infos {
+		infos[i] = s.relInfo(full, info)
+	}
+	return infos, err
+}
+
+func (s *subFS) Readdirnames(dirname string, n int) ([]string, error) {
+	full, err := s.join(dirname)
+	if err != nil {
+		return nil, err
+	}
+	return s.forwarder.Readdirnames(full, n)
+}
+
+func (s *subFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := s.join(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.forwarder.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	return WithPath(info, s.rel(full)), nil
+}
+
+func (s *subFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	full, err := s.join(root)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Walk(full, func(name string, info fs.FileInfo, err error) error {
+		rel := s.rel(name)
+		if info != nil {
+			info = WithPath(info, rel)
+		}
+		return walkFn(rel, info, err)
+	})
+}
+
+func (s *subFS) Mkdir(dirname string, perm fs.FileMode) error {
+	full, err := s.write("mkdir", dirname)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Mkdir(full, perm)
+}
+
+func (s *subFS) MkdirAll(dirname string, perm fs.FileMode) error {
+	full, err := s.write("mkdir", dirname)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.MkdirAll(full, perm)
+}
+
+func (s *subFS) Create(name string) (io.WriteCloser, error) {
+	full, err := s.write("create", name)
+	if err != nil {
+		return nil, err
+	}
+	return s.forwarder.Create(full)
+}
+
+func (s *subFS) Rename(oldPath, newPath string) error {
+	oldFull, err := s.write("rename", oldPath)
+	if err != nil {
+		return err
+	}
+	newFull, err := s.write("rename", newPath)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Rename(oldFull, newFull)
+}
+
+func (s *subFS) Remove(name string) error {
+	full, err := s.write("remove", name)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Remove(full)
+}
+
+// RemoveAll refuses to remove the root, which is base itself.
+func (s *subFS) RemoveAll(name string) error {
+	full, err := s.write("remove", name)
+	if err != nil {
+		return err
+	}
+	if full == s.base {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	return s.forwarder.RemoveAll(full)
+}
+
+func (s *subFS) RemoveBatch(remotes []string) []error {
+	var errs []error
+	var fulls []string
+	var index []int
+	for i, remote := range remotes {
+		full, err := s.write("remove", remote)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(remotes))
+			}
+			errs[i] = err
+			continue
+		}
+		fulls = append(fulls, full)
+		index = append(index, i)
+	}
+	if len(fulls) == 0 {
+		return errs
+	}
+	for j, err := range s.forwarder.RemoveBatch(fulls) {
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(remotes))
+			}
+			errs[index[j]] = err
+		}
+	}
+	return errs
+}
+
+// Put reads local from the local file system, which is not restricted.
+func (s *subFS) Put(local, remote string) error {
+	full, err := s.write("put", remote)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Put(local, full)
+}
+
+func (s *subFS) PutStream(reader io.Reader, remote string) error {
+	full, err := s.write("put", remote)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.PutStream(reader, full)
+}
+
+func (s *subFS) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	full, err := s.write("put", remote)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.PutStreamWithOptions(reader, full, opts)
+}
+
+func (s *subFS) PutStreamWithContentType(reader io.Reader, remote string, contentType string) error {
+	full, err := s.write("put", remote)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.PutStreamWithContentType(reader, full, contentType)
+}
+
+func (s *subFS) PutEmpty(remote string) error {
+	full, err := s.write("put", remote)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.PutEmpty(full)
+}
+
+func (s *subFS) Exist(remote string) bool {
+	full, err := s.join(remote)
+	if err != nil {
+		return false
+	}
+	return s.forwarder.Exist(full)
+}
+
+func (s *subFS) ExistE(remote string) (bool, error) {
+	full, err := s.join(remote)
+	if err != nil {
+		return false, err
+	}
+	return s.forwarder.ExistE(full)
+}
+
+func (s *subFS) Copy(src, dst string) error {
+	srcFull, err := s.join(src)
+	if err != nil {
+		return err
+	}
+	dstFull, err := s.write("copy", dst)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Copy(srcFull, dstFull)
+}
+
+func (s *subFS) Move(src, dst string) error {
+	srcFull, err := s.write("move", src)
+	if err != nil {
+		return err
+	}
+	dstFull, err := s.write("move", dst)
+	if err != nil {
+		return err
+	}
+	return s.forwarder.Move(srcFull, dstFull)
+}
+
+func (s *subFS) ReaddirPrefix(dirname, prefix string, n int) ([]fs.FileInfo, error) {
+	full, err := s.join(dirname)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := s.forwarder.ReaddirPrefix(full, prefix, n)
+	for i, info := range infos {
+		infos[i] = s.relInfo(full, info)
+	}
+	return infos, err
+}
+
+func (s *subFS) ReaddirnamesPrefix(dirname, prefix string, n int) ([]string, error) {
+	full, err := s.join(dirname)
+	if err != nil {
+		return nil, err
+	}
+	return s.forwarder.ReaddirnamesPrefix(full, prefix, n)
+}
+
+func (s *subFS) PresignGet(name string, ttl time.Duration) (string, error) {
+	full, err := s.join(name)
+	if err != nil {
+		return "", err
+	}
+	return s.forwarder.PresignGet(full, ttl)
+}
+
+func (s *subFS) PresignPut(name string, ttl time.Duration) (string, error) {
+	full, err := s.write("presign", name)
+	if err != nil {
+		return "", err
+	}
+	return s.forwarder.PresignPut(full, ttl)
+}
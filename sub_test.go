@@ -0,0 +1,94 @@
+package fileop_test
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func TestSubPath(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	for _, tc := range []struct {
+		base, name, want string
+	}{
+		{"/base", "a/b", "/base/a/b"},
+		{"/base", "/a/b", "/base/a/b"},
+		{"/base", "a/../b", "/base/b"},
+		{"/base", "", "/base"},
+		{"/base", "..", ""},
+		{"/base", "a/../../etc", ""},
+		{"/base", "/../base/a", ""},
+		{"bucket", "../other", ""},
+		{"bucket", "x/./y/", "bucket/x/y"},
+	} {
+		got, err := fileop.SubPath(tc.base, tc.name)
+		if tc.want == "" {
+			assert.ErrorIs(err, fileop.ErrPathEscape, tc.name)
+			assert.ErrorIs(err, fs.ErrPermission, tc.name)
+			continue
+		}
+		assert.NoError(err, tc.name)
+		assert.Equal(tc.want, got, tc.name)
+	}
+}
+
+func TestSub(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	assert.NoError(mfs.MkdirAll("/secret", 0755))
+	fsys, err := fileop.Sub[fileop.FileSystem](mfs, "/tenant")
+	assert.NoError(err)
+
+	wt, err := fileop.NewFileWriter(fsys, "/d/a.txt", 0, fileop.NONE)
+	assert.NoError(err)
+	_, err = wt.Write([]byte("aaa"))
+	assert.NoError(err)
+	assert.NoError(wt.Close())
+	_, err = mfs.Stat("/tenant/d/a.txt")
+	assert.NoError(err)
+
+	info, err := fsys.Stat("d/a.txt")
+	assert.NoError(err)
+	assert.Equal("/d/a.txt", fileop.InfoPath("/d", info))
+
+	infos, err := fsys.Readdir("/d", 0)
+	assert.NoError(err)
+	assert.Len(infos, 1)
+	assert.Equal("/d/a.txt", fileop.InfoPath("/d", infos[0]))
+
+	var walked []string
+	assert.NoError(fsys.Walk("/", func(name string, _ fs.FileInfo, err error) error {
+		walked = append(walked, filepath.ToSlash(name))
+		return err
+	}))
+	assert.Equal([]string{"/", "/d", "/d/a.txt"}, walked)
+
+	_, err = fsys.Readdir("../secret", 0)
+	assert.ErrorIs(err, fileop.ErrPathEscape)
+	_, err = fsys.Open("/d/../../tenant/d/a.txt")
+	assert.ErrorIs(err, fileop.ErrPathEscape)
+	assert.ErrorIs(fsys.Rename("/d/a.txt", "../a.txt"), fileop.ErrPathEscape)
+	assert.ErrorIs(fsys.RemoveAll("/"), fs.ErrPermission)
+	_, err = mfs.Stat("/tenant/d/a.txt")
+	assert.NoError(err)
+
+	ro, err := fileop.SubWithOptions[fileop.FileSystem](mfs, "/tenant/d", fileop.SubOptions{ReadOnly: true})
+	assert.NoError(err)
+	rd, err := ro.Open("a.txt")
+	assert.NoError(err)
+	assert.NoError(rd.Close())
+	_, err = ro.Create("b.txt")
+	assert.ErrorIs(err, fileop.ErrReadOnly)
+	assert.ErrorIs(ro.Remove("a.txt"), fs.ErrPermission)
+	assert.ErrorIs(ro.MkdirAll("x", 0755), fileop.ErrReadOnly)
+}
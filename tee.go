@@ -0,0 +1,267 @@
+package fileop
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// TeePolicy decides when a write to a Tee succeeds.
+type TeePolicy int
+
+const (
+	// TeePrimary succeeds when the primary target succeeds. Failures of
+	// secondaries are only reported to OnFailure.
+	TeePrimary TeePolicy = iota
+	// TeeAll succeeds only when every target succeeds.
+	TeeAll
+)
+
+// TeeOptions configures NewTee.
+type TeeOptions struct {
+	Primary     ITargetUploader
+	Secondaries []ITargetUploader
+	Policy      TeePolicy
+
+	// OnFailure is called for every failed write of a secondary, to repair
+	// it later. It may be called concurrently.
+	OnFailure func(TeeFailure)
+}
+
+// TeeFailure describes a failed write of a secondary target.
+type TeeFailure struct {
+	Op        Op
+	Remote    string
+	Secondary int // index in TeeOptions.Secondaries
+	Err       error
+}
+
+// TeeError is returned by a Tee with the TeeAll policy when a target
+// failed. Errs holds the error of every target, primary first, nil for
+// those that succeeded.
+type TeeError struct {
+	Op     Op
+	Remote string
+	Errs   []error
+}
+
+func (e *TeeError) Error() string {
+	var failed []string
+	for i, err := range e.Errs {
+		if err == nil {
+			continue
+		}
+		if i == 0 {
+			failed = append(failed, fmt.Sprintf("primary: %v", err))
+		} else {
+			failed = append(failed, fmt.Sprintf("secondary %d: %v", i-1, err))
+		}
+	}
+	return fmt.Sprintf("tee %s %s: %s", e.Op, e.Remote, strings.Join(failed, "; "))
+}
+
+// Unwrap returns the errors of the failed targets, without the nil entries
+// of Errs.
+func (e *TeeError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Tee is an ITargetUploader writing to a primary and several secondary
+// targets at once, e.g. to migrate between backends. Uploads and removes go
+// to all targets concurrently; Stat and Exist ask the primary only.
+type Tee struct {
+	targets []ITargetUploader // primary first
+	opts    TeeOptions
+}
+
+var _ ITargetUploader = (*Tee)(nil)
+
+// NewTee creates a tee of opts.Primary and opts.Secondaries.
+func NewTee(opts TeeOptions) *Tee {
+	targets := append([]ITargetUploader{opts.Primary}, opts.Secondaries...)
+	return &Tee{targets: targets, opts: opts}
+}
+
+// each runs fn for every target concurrently and returns their errors.
+func (t *Tee) each(fn func(i int, target ITargetUploader) error) []error {
+	errs := make([]error, len(t.targets))
+	var wg sync.WaitGroup
+	for i, target := range t.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i, target)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// result reports failed secondaries and applies the policy to errs.
+func (t *Tee) result(op Op, remote string, errs []error) error {
+	failed := false
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed = true
+		if i > 0 && t.opts.OnFailure != nil {
+			t.opts.OnFailure(TeeFailure{Op: op, Remote: remote, Secondary: i - 1, Err: err})
+		}
+	}
+	switch {
+	case t.opts.Policy == TeePrimary:
+		return errs[0]
+	case failed:
+		return &TeeError{Op: op, Remote: remote, Errs: errs}
+	}
+	return nil
+}
+
+// Put uploads local to every target. Each target reads the file itself.
+func (t *Tee) Put(local, remote string) error {
+	errs := t.each(func(_ int, target ITargetUploader) error {
+		return target.Put(local, remote)
+	})
+	return t.result(OpPut, remote, errs)
+}
+
+// PutStream streams reader to every target concurrently without buffering
+// it, so the slowest target sets the pace. Targets that fail stop
+// receiving data while the others continue.
+func (t *Tee) PutStream(reader io.Reader, remote string) error {
+	return t.stream(reader, remote, func(target ITargetUploader, rd io.Reader) error {
+		return target.PutStream(rd, remote)
+	})
+}
+
+// PutStreamWithOptions is PutStream with object properties, uploaded with
+// PutStream to targets that do not implement IOptionsUploader.
+func (t *Tee) PutStreamWithOptions(reader io.Reader, remote string, opts PutOptions) error {
+	return t.stream(reader, remote, func(target ITargetUploader, rd io.Reader) error {
+		if u, ok := As[IOptionsUploader](target); ok {
+			return u.PutStreamWithOptions(rd, remote, opts)
+		}
+		return target.PutStream(rd, remote)
+	})
+}
+
+func (t *Tee) stream(reader io.Reader, remote string, put func(ITargetUploader, io.Reader) error) error {
+	writers := make([]io.Writer, len(t.targets))
+	pipes := make([]*io.PipeWriter, len(t.targets))
+	readers := make([]*io.PipeReader, len(t.targets))
+	for i := range t.targets {
+		readers[i], pipes[i] = io.Pipe()
+		writers[i] = &teeWriter{w: pipes[i]}
+	}
+
+	var copyErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, copyErr = io.Copy(io.MultiWriter(writers...), reader)
+		for _, pw := range pipes {
+			_ = pw.CloseWithError(copyErr)
+		}
+	}()
+
+	errs := t.each(func(i int, target ITargetUploader) error {
+		err := put(target, readers[i])
+		// unblock the copy if the target stopped reading early
+		_ = readers[i].CloseWithError(errTeeTargetDone)
+		return err
+	})
+	<-done
+	if copyErr != nil {
+		return fmt.Errorf("read %s: %w", remote, copyErr)
+	}
+	return t.result(OpPutStream, remote, errs)
+}
+
+var errTeeTargetDone = errors.New("tee target done")
+
+// teeWriter drops the data once the reading target is done, so that a
+// failed target does not stop the others.
+type teeWriter struct {
+	w    io.Writer
+	done bool
+}
+
+func (w *teeWriter) Write(p []byte) (int, error) {
+	if !w.done {
+		if _, err := w.w.Write(p); err != nil {
+			w.done = true
+		}
+	}
+	return len(p), nil
+}
+
+func (t *Tee) PutEmpty(remote string) error {
+	errs := t.each(func(_ int, target ITargetUploader) error {
+		return target.PutEmpty(remote)
+	})
+	return t.result(OpPutEmpty, remote, errs)
+}
+
+func (t *Tee) Remove(remote string) error {
+	errs := t.each(func(_ int, target ITargetUploader) error {
+		return target.Remove(remote)
+	})
+	return t.result(OpRemove, remote, errs)
+}
+
+// RemoveBatch removes remotes from every target and applies the policy to
+// each remote.
+func (t *Tee) RemoveBatch(remotes []string) []error {
+	batches := make([][]error, len(t.targets))
+	t.each(func(i int, target ITargetUploader) error {
+		batches[i] = target.RemoveBatch(remotes)
+		return nil
+	})
+	var errs []error
+	for j, remote := range remotes {
+		targetErrs := make([]error, len(t.targets))
+		for i, batch := range batches {
+			if batch != nil {
+				targetErrs[i] = batch[j]
+			}
+		}
+		if err := t.result(OpRemoveBatch, remote, targetErrs); err != nil {
+			if errs == nil {
+				errs = make([]error, len(remotes))
+			}
+			errs[j] = err
+		}
+	}
+	return errs
+}
+
+func (t *Tee) Stat(name string) (fs.FileInfo, error) {
+	return t.opts.Primary.Stat(name)
+}
+
+func (t *Tee) Exist(remote string) bool {
+	return t.opts.Primary.Exist(remote)
+}
+
+func (t *Tee) ExistE(remote string) (bool, error) {
+	return t.opts.Primary.ExistE(remote)
+}
+
+// Close closes every target.
+func (t *Tee) Close() error {
+	var errs []error
+	for _, target := range t.targets {
+		errs = append(errs, target.Close())
+	}
+	return errors.Join(errs...)
+}
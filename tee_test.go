@@ -0,0 +1,163 @@
+package fileop_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+)
+
+// memTarget is an ITargetUploader keeping uploads in memory. Uploads fail
+// with err after reading failAfter bytes when err is set.
+type memTarget struct {
+	err       error
+	failAfter int
+
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newMemTarget() *memTarget {
+	return &memTarget{data: make(map[string]string)}
+}
+
+func (m *memTarget) get(remote string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.data[remote]
+	return s, ok
+}
+
+func (m *memTarget) Put(local, remote string) error {
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return m.PutStream(f, remote)
+}
+
+func (m *memTarget) PutStream(reader io.Reader, remote string) error {
+	if m.err != nil {
+		_, _ = io.CopyN(io.Discard, reader, int64(m.failAfter))
+		return m.err
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[remote] = string(b)
+	return nil
+}
+
+func (m *memTarget) PutEmpty(remote string) error {
+	return m.PutStream(strings.NewReader(""), remote)
+}
+
+func (m *memTarget) Stat(name string) (fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fileop.ErrUnsupported}
+}
+
+func (m *memTarget) Exist(remote string) bool {
+	_, ok := m.get(remote)
+	return ok
+}
+
+func (m *memTarget) ExistE(remote string) (bool, error) {
+	return m.Exist(remote), nil
+}
+
+func (m *memTarget) Remove(remote string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, remote)
+	return nil
+}
+
+func (m *memTarget) RemoveBatch(remotes []string) []error {
+	var errs []error
+	for i, remote := range remotes {
+		if err := m.Remove(remote); err != nil {
+			if errs == nil {
+				errs = make([]error, len(remotes))
+			}
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+func (m *memTarget) Close() error { return nil }
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	errBroken := errors.New("broken")
+	primary, ok, broken := newMemTarget(), newMemTarget(), newMemTarget()
+	broken.err, broken.failAfter = errBroken, 10
+
+	var mu sync.Mutex
+	var failures []fileop.TeeFailure
+	opts := fileop.TeeOptions{
+		Primary:     primary,
+		Secondaries: []fileop.ITargetUploader{broken, ok},
+		OnFailure: func(f fileop.TeeFailure) {
+			mu.Lock()
+			defer mu.Unlock()
+			failures = append(failures, f)
+		},
+	}
+	tee := fileop.NewTee(opts)
+
+	// the broken target stops reading early without blocking the others
+	content := strings.Repeat("x", 1<<20)
+	assert.NoError(tee.PutStream(strings.NewReader(content), "/a.txt"))
+	for _, target := range []*memTarget{primary, ok} {
+		got, _ := target.get("/a.txt")
+		assert.Equal(content, got)
+	}
+	assert.Len(failures, 1)
+	assert.Equal(fileop.TeeFailure{Op: fileop.OpPutStream, Remote: "/a.txt", Secondary: 0, Err: errBroken}, failures[0])
+
+	local := filepath.Join(t.TempDir(), "b.txt")
+	assert.NoError(os.WriteFile(local, []byte("b"), 0644))
+	assert.NoError(tee.Put(local, "/b.txt"))
+	got, _ := ok.get("/b.txt")
+	assert.Equal("b", got)
+
+	// all must succeed
+	opts.Policy = fileop.TeeAll
+	tee = fileop.NewTee(opts)
+	err := tee.PutEmpty("/c.txt")
+	var teeErr *fileop.TeeError
+	assert.ErrorAs(err, &teeErr)
+	assert.ErrorIs(err, errBroken)
+	assert.Equal([]error{nil, errBroken, nil}, teeErr.Errs)
+	assert.Equal([]error{errBroken}, teeErr.Unwrap())
+	assert.True(primary.Exist("/c.txt"))
+
+	errs := tee.RemoveBatch([]string{"/a.txt", "/b.txt"})
+	assert.Len(errs, 2)
+	assert.ErrorIs(errs[0], errBroken)
+	assert.False(ok.Exist("/a.txt"))
+
+	// read errors of the source fail the upload
+	errRead := errors.New("read")
+	err = tee.PutStream(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(errRead)), "/d.txt")
+	assert.ErrorIs(err, errRead)
+}
@@ -0,0 +1,228 @@
+package fileop
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// WalkFileSystem is the subset of FileSystem required by WalkParallel.
+type WalkFileSystem interface {
+	DirReader
+	Stater
+}
+
+// WalkParallel walks the file tree rooted at root like filepath.Walk, but
+// lists up to workers directories concurrently. It is meant for backends
+// where every Readdir is a network round trip (HDFS namenode, object store).
+//
+// Differences from filepath.Walk:
+//   - walkFn is called from multiple goroutines and must be safe for
+//     concurrent use. Calls are unordered: a directory is always reported
+//     before its entries, but siblings and subtrees interleave arbitrarily.
+//   - Returning filepath.SkipDir for a directory skips its contents; for a
+//     file it skips the remaining entries of the same directory that have
+//     not been reported yet.
+//   - Returning filepath.SkipAll stops the walk and WalkParallel returns nil.
+//     Any other non-nil error stops the walk and is returned. Callbacks that
+//     are already running are allowed to finish.
+//
+// As with filepath.Walk, a directory is reported before it is listed, and
+// reported a second time with the error when listing it fails. When root
+// can not be stated, walkFn is called once with the error and the walk ends.
+//
+// If workers is less than 1, a single worker is used.
+func WalkParallel(fsys WalkFileSystem, root string, workers int, walkFn filepath.WalkFunc) error {
+	return WalkParallelContext(context.Background(), fsys, root, workers, walkFn)
+}
+
+// WalkParallelContext is like WalkParallel but stops when ctx is done,
+// returning ctx.Err().
+func WalkParallelContext(ctx context.Context, fsys WalkFileSystem, root string, workers int, walkFn filepath.WalkFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walkFn(root, info, nil)
+	}
+	if err != nil || info == nil || !info.IsDir() {
+		if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+			return nil
+		}
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w := &parallelWalker{
+		fsys:    fsys,
+		walkFn:  walkFn,
+		queue:   []walkItem{{path: root, info: info}},
+		pending: 1,
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	stopped := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(stopped)
+		w.stop(ctx.Err())
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	if !stop() {
+		// the callback has started and may still be recording ctx.Err()
+		<-stopped
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+type walkItem struct {
+	path string
+	info fs.FileInfo
+}
+
+type parallelWalker struct {
+	fsys   WalkFileSystem
+	walkFn filepath.WalkFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []walkItem
+	pending int // directories queued or being listed
+	stopped bool
+	err     error
+}
+
+// stop ends the walk, recording err unless it is SkipAll. Only the first
+// call has an effect.
+func (w *parallelWalker) stop(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	if !errors.Is(err, filepath.SkipAll) {
+		w.err = err
+	}
+	w.cond.Broadcast()
+}
+
+func (w *parallelWalker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+func (w *parallelWalker) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 && !w.stopped {
+			w.cond.Wait()
+		}
+		if w.stopped || w.pending == 0 {
+			w.mu.Unlock()
+			return
+		}
+		item := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		subdirs := w.walkDir(item)
+
+		w.mu.Lock()
+		w.queue = append(w.queue, subdirs...)
+		w.pending += len(subdirs) - 1
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// walkDir lists a single directory, reports its entries and returns the
+// subdirectories that should be descended into.
+func (w *parallelWalker) walkDir(dir walkItem) []walkItem {
+	infos, err := w.fsys.Readdir(dir.path, 0)
+	if err != nil {
+		if err := w.walkFn(dir.path, dir.info, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+			w.stop(err)
+		}
+		return nil
+	}
+
+	var subdirs []walkItem
+	for _, info := range infos {
+		if w.isStopped() {
+			return nil
+		}
+
+		name := path.Join(dir.path, info.Name())
+		if err := w.walkFn(name, info, nil); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				if info.IsDir() {
+					continue
+				}
+				break
+			}
+			w.stop(err)
+			return nil
+		}
+		if info.IsDir() {
+			subdirs = append(subdirs, walkItem{path: name, info: info})
+		}
+	}
+	return subdirs
+}
+
+// Walk walks the file tree rooted at root like filepath.Walk, calling
+// walkFn in lexical order. It only needs Stat and Readdir, for file systems
+// without a Walk method of their own.
+func Walk(fsys WalkFileSystem, root string, walkFn filepath.WalkFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fsys, root, info, walkFn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walk(fsys WalkFileSystem, name string, info fs.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(name, info, nil)
+	}
+	infos, err := fsys.Readdir(name, 0)
+	if err := walkFn(name, info, err); err != nil || infos == nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	for _, info := range infos {
+		err := walk(fsys, path.Join(name, info.Name()), info, walkFn)
+		if err != nil && (!info.IsDir() || !errors.Is(err, filepath.SkipDir)) {
+			return err
+		}
+	}
+	return nil
+}
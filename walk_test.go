@@ -0,0 +1,192 @@
+package fileop_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/marsgopher/fileop"
+	"github.com/marsgopher/fileop/integration/afero"
+)
+
+func newWalkTestFS(t *testing.T) *afero.Handler {
+	assert := require.New(t)
+
+	mfs, err := afero.New(afero.Memory)
+	assert.NoError(err)
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			for k := 0; k < 3; k++ {
+				fp := filepath.Join("root", fmt.Sprintf("%02d", i), fmt.Sprintf("%02d", j), fmt.Sprintf("%d.txt", k))
+				wt, err := fileop.NewFileWriter(mfs, fp, 0, fileop.NONE)
+				assert.NoError(err)
+				assert.NoError(wt.Close())
+			}
+		}
+	}
+	return mfs
+}
+
+func TestWalkParallelMatchesWalk(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	mfs := newWalkTestFS(t)
+
+	var want []string
+	assert.NoError(mfs.Walk("root", func(path string, _ fs.FileInfo, err error) error {
+		want = append(want, path)
+		return err
+	}))
+
+	var mu sync.Mutex
+	var got []string
+	assert.NoError(fileop.WalkParallel(mfs, "root", 4, func(path string, _ fs.FileInfo, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, path)
+		return err
+	}))
+
+	sort.Strings(want)
+	sort.Strings(got)
+	assert.Equal(want, got)
+}
+
+func TestWalkParallelSkipDir(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	mfs := newWalkTestFS(t)
+
+	var mu sync.Mutex
+	var got []string
+	assert.NoError(fileop.WalkParallel(mfs, "root", 4, func(path string, info fs.FileInfo, err error) error {
+		if info.IsDir() && info.Name() == "01" {
+			return filepath.SkipDir
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, path)
+		return nil
+	}))
+
+	for _, p := range got {
+		assert.NotContains(strings.Split(filepath.ToSlash(p), "/"), "01")
+	}
+	// root + 4 top dirs * (1 + 4 sub dirs * (1 + 3 files))
+	assert.Len(got, 1+4*(1+4*(1+3)))
+}
+
+func TestWalkParallelStopOnError(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	mfs := newWalkTestFS(t)
+
+	errStop := errors.New("stop")
+	err := fileop.WalkParallel(mfs, "root", 4, func(path string, info fs.FileInfo, err error) error {
+		if !info.IsDir() {
+			return errStop
+		}
+		return nil
+	})
+	assert.ErrorIs(err, errStop)
+
+	err = fileop.WalkParallel(mfs, "root", 4, func(path string, info fs.FileInfo, err error) error {
+		if !info.IsDir() {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	assert.NoError(err)
+
+	err = fileop.WalkParallel(mfs, "missing", 4, func(path string, info fs.FileInfo, err error) error {
+		return err
+	})
+	assert.ErrorIs(err, fs.ErrNotExist)
+
+	// a missing root ends the walk even if walkFn ignores the error
+	var calls int
+	err = fileop.WalkParallel(mfs, "missing", 4, func(path string, info fs.FileInfo, err error) error {
+		calls++
+		assert.Nil(info)
+		assert.ErrorIs(err, fs.ErrNotExist)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(1, calls)
+}
+
+// unlistableDirFS fails Readdir of dir.
+type unlistableDirFS struct {
+	*afero.Handler
+	dir string
+}
+
+var errUnlistable = errors.New("unlistable")
+
+func (u unlistableDirFS) Readdir(dirname string, n int) ([]fs.FileInfo, error) {
+	if dirname == u.dir {
+		return nil, errUnlistable
+	}
+	return u.Handler.Readdir(dirname, n)
+}
+
+func TestWalkParallelReaddirError(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	fsys := unlistableDirFS{Handler: newWalkTestFS(t), dir: "root/01"}
+
+	var mu sync.Mutex
+	var errs []error
+	var files int
+	assert.NoError(fileop.WalkParallel(fsys, "root", 4, func(path string, info fs.FileInfo, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if path == "root/01" {
+			errs = append(errs, err)
+		} else if !info.IsDir() {
+			files++
+		}
+		return nil
+	}))
+	// reported when found and again with the listing error, like filepath.Walk
+	assert.Equal([]error{nil, errUnlistable}, errs)
+	assert.Equal(4*5*3, files)
+}
+
+func TestWalkParallelContext(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+	mfs := newWalkTestFS(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var mu sync.Mutex
+	var walked []string
+	err := fileop.WalkParallelContext(ctx, mfs, "root", 4, func(path string, info fs.FileInfo, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		walked = append(walked, path)
+		return err
+	})
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal([]string{"root"}, walked)
+
+	// canceling races with the end of the walk, which may complete
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	err = fileop.WalkParallelContext(ctx, mfs, "root", 4, func(path string, info fs.FileInfo, err error) error {
+		if !info.IsDir() {
+			cancel()
+		}
+		return err
+	})
+	assert.True(err == nil || errors.Is(err, context.Canceled), err)
+}